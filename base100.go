@@ -4,8 +4,14 @@
 package base100
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
+	"unsafe"
 )
 
 const (
@@ -14,18 +20,312 @@ const (
 	encodedByteSize = 4    // size of single "raw" byte after base100 encoding
 )
 
+/* ENCODING */
+
+// An Encoding is a base100 encoding with a particular set of options, such as
+// input validation or line wrapping. Encodings are immutable, new ones are
+// derived from existing ones via methods such as Strict and WithWrap.
+//
+// The package level functions such as Encode and Decode all use StdEncoding,
+// apart from the Strict variants, which use StdEncoding.Strict().
+type Encoding struct {
+	strict       bool   // validate input when decoding
+	whitespace   bool   // skip whitespace (and U+FE0F) when decoding
+	skinToneSafe bool   // remap skin tone modifiers, see SkinToneSafe
+	presentation bool   // add variation selectors, see EmojiPresentation
+	formatChars  bool   // skip invisible format characters when decoding
+	wrap         int    // runes per line when encoding, 0 to disable wrapping
+	eol          string // line terminator when wrapping
+}
+
+// StdEncoding is the standard base100 encoding, as implemented by the original
+// Rust version. It does not validate input when decoding, but does skip over
+// whitespace, as well as the U+FE0F variation selectors which EmojiPresentation
+// adds, and does not wrap lines when encoding.
+var StdEncoding = &Encoding{whitespace: true}
+
+// strictEncoding backs the package level Strict functions.
+var strictEncoding = StdEncoding.Strict()
+
+// Strict creates a new encoding identical to enc except that decoding verifies
+// that every rune of input is valid base100, failing with a CorruptInputError
+// otherwise. See DecodeStrict.
+func (enc Encoding) Strict() *Encoding {
+	enc.strict = true
+	return &enc
+}
+
+// SkinToneSafeEncoding is StdEncoding with the SkinToneSafe alphabet.
+var SkinToneSafeEncoding = StdEncoding.SkinToneSafe()
+
+// SkinToneSafe creates a new encoding identical to enc except that it uses an
+// alternate alphabet which avoids the emoji skin tone modifiers.
+//
+// In the standard alphabet the bytes 0x04 through 0x08 are encoded as the five
+// Fitzpatrick skin tone modifiers U+1F3FB through U+1F3FF, which most renderers
+// merge into the preceding emoji, and which tend to get lost or rearranged when
+// copied and pasted. These are the only modifier or component code points in
+// the alphabet. The alternate alphabet instead encodes those bytes as the
+// standalone emoji U+1F4F7 through U+1F4FB (📷📸📹📺📻), which fall just past the
+// end of the standard alphabet. All other bytes are encoded as usual.
+//
+// When decoding, both forms of those five bytes are accepted. Output encoded
+// using the alternate alphabet can not be decoded by the standard one.
+func (enc Encoding) SkinToneSafe() *Encoding {
+	enc.skinToneSafe = true
+	return &enc
+}
+
+// EmojiPresentation creates a new encoding identical to enc except that the
+// runes which default to text rather than emoji presentation are followed by
+// U+FE0F VARIATION SELECTOR-16, so that they are rendered as color emoji of
+// uniform width alongside the rest.
+//
+// Only three runes of the alphabet are affected: U+1F3F7 🏷 (byte 0x00),
+// U+1F43F 🐿 (byte 0x48) and U+1F441 👁 (byte 0x4a). Since the encoded length
+// then depends on the content of the input, EncodedLen becomes an upper bound,
+// and Encode reports the actual length.
+//
+// When decoding, U+FE0F is ignored wherever it occurs, so that input with and
+// without variation selectors is accepted alike. The same goes for any encoding
+// which skips whitespace, such as StdEncoding, so the output of this one can be
+// decoded by them as well.
+func (enc Encoding) EmojiPresentation() *Encoding {
+	enc.presentation = true
+	return &enc
+}
+
+// IgnoreFormatChars creates a new encoding identical to enc except that when
+// decoding, the invisible format characters which some platforms insert in
+// between emoji are ignored wherever they occur, much like whitespace. These
+// are U+FE0F VARIATION SELECTOR-16, U+FE0E VARIATION SELECTOR-15 and U+200D
+// ZERO WIDTH JOINER.
+//
+// Without this, they either misalign every rune of input which follows, or in
+// the case of a strict encoding, cause decoding to fail. Only U+FE0F is skipped
+// regardless, by encodings which skip whitespace or use EmojiPresentation.
+func (enc Encoding) IgnoreFormatChars() *Encoding {
+	enc.formatChars = true
+	return &enc
+}
+
+// WithWhitespace creates a new encoding identical to enc except with skipping
+// of whitespace (\r, \n, space and tab) when decoding enabled or disabled.
+// U+FE0F VARIATION SELECTOR-16, which can never be part of a base100 rune, is
+// skipped along with whitespace, see EmojiPresentation.
+//
+// With whitespace skipping disabled, whitespace is treated like any other
+// unexpected input: it is rejected by a strict encoding, and decoded into
+// garbage otherwise, as the Rust version would.
+func (enc Encoding) WithWhitespace(skip bool) *Encoding {
+	enc.whitespace = skip
+	return &enc
+}
+
+// WithWrap creates a new encoding identical to enc except that encoded output
+// is broken into lines of width runes, each terminated by eol. A width of zero
+// disables wrapping. See NewWrappingEncoder. WithWrap panics if width is
+// negative.
+//
+// Wrapped output can only be decoded by an encoding which skips whitespace.
+func (enc Encoding) WithWrap(width int, eol LineEnding) *Encoding {
+	if width < 0 {
+		panic("base100: negative wrap width")
+	}
+	enc.wrap = width
+	enc.eol = eol.String()
+	return &enc
+}
+
+// LineEnding selects the line terminator used when wrapping encoded output.
+type LineEnding int
+
+const (
+	LF   LineEnding = iota // "\n"
+	CRLF                   // "\r\n"
+)
+
+// String returns the line terminator itself.
+func (le LineEnding) String() string {
+	if le == CRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+/* ERRORS */
+
+// ErrShortDst is returned when a destination buffer is too short to receive
+// the decoded result.
+var ErrShortDst = errors.New("base100: destination buffer too short")
+
+// CorruptInputError reports the position of invalid base100 data in the input.
+type CorruptInputError struct {
+	Offset int64 // byte offset of the invalid rune within the input
+	Index  int64 // index of the invalid rune, counted in runes of input
+}
+
+func (e CorruptInputError) Error() string {
+	return fmt.Sprintf("base100: illegal data at input byte %d (rune %d)", e.Offset, e.Index)
+}
+
+// TruncatedInputError reports that the input ended partway through a base100
+// rune. Its value is the number of dangling bytes at the end of the input.
+//
+// It wraps io.ErrUnexpectedEOF, so errors.Is(err, io.ErrUnexpectedEOF) holds.
+type TruncatedInputError int
+
+func (e TruncatedInputError) Error() string {
+	return fmt.Sprintf("base100: input truncated with %d trailing bytes", int(e))
+}
+
+func (e TruncatedInputError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// trailingError returns the error for an incomplete rune of 1-3 bytes found at
+// the given byte offset and rune index at the end of the input. In strict mode
+// bytes which could never start a base100 rune are reported as corrupt, not
+// merely truncated.
+func (enc *Encoding) trailingError(tail []byte, offset, index int64) error {
+	if enc.strict && !validPrefix(tail) && !enc.skippablePrefix(tail) {
+		return CorruptInputError{Offset: offset, Index: index}
+	}
+	return TruncatedInputError(len(tail))
+}
+
+// skipsVS16 reports whether enc skips over U+FE0F when decoding.
+func (enc *Encoding) skipsVS16() bool {
+	return enc.whitespace || enc.presentation || enc.formatChars
+}
+
+// skippablePrefix reports whether b is a prefix of one of the characters other
+// than whitespace which enc skips over when decoding.
+func (enc *Encoding) skippablePrefix(b []byte) bool {
+	return enc.skipsVS16() && strings.HasPrefix(vs16, string(b)) ||
+		enc.formatChars && (strings.HasPrefix(vs15, string(b)) || strings.HasPrefix(zwj, string(b)))
+}
+
+// validPrefix reports whether b is a prefix of a valid base100 rune.
+func validPrefix(b []byte) bool {
+	switch {
+	case len(b) > 0 && b[0] != fixedByte1:
+		return false
+	case len(b) > 1 && b[1] != fixedByte2:
+		return false
+	case len(b) > 2 && (b[2] < 0x8f || b[2] > 0x93):
+		return false
+	}
+	return true
+}
+
 /* ENCODE */
 
 // Encode encodes src to its base100 encoding, writing EncodedLen(len(src))
 // bytes to dst.
 func Encode(dst, src []byte) {
-	// We use this alternative loop and reslicing to help the compiler
-	// perform bounds check elimination.
-	//
-	// Thanks to the #performance channel on the Gophers Slack!
-	for len(dst) >= encodedByteSize && len(src) >= 1 {
-		b := src[0]
+	StdEncoding.Encode(dst, src)
+}
 
+// Encode encodes src using the encoding enc, writing EncodedLen(len(src))
+// bytes to dst, and returns the number of bytes written. This can only be less
+// than EncodedLen(len(src)) if enc uses EmojiPresentation. If enc wraps lines,
+// every line including the last is terminated.
+func (enc *Encoding) Encode(dst, src []byte) int {
+	if enc.wrap == 0 {
+		return enc.encodeRunes(dst, src)
+	}
+	var n int
+	for len(src) > 0 {
+		lineSize := min(len(src), enc.wrap)
+		n += enc.encodeRunes(dst[n:], src[:lineSize])
+		n += copy(dst[n:], enc.eol)
+		src = src[lineSize:]
+	}
+	return n
+}
+
+// Invisible format characters which may show up in between emoji.
+const (
+	vs15 = "\ufe0e" // VARIATION SELECTOR-15, requests text presentation
+	vs16 = "\ufe0f" // VARIATION SELECTOR-16, requests emoji presentation
+	zwj  = "\u200d" // ZERO WIDTH JOINER, combines emoji into a single glyph
+)
+
+// maxRuneLen returns the maximum number of bytes a single input byte may be
+// encoded to by enc, not counting line terminators.
+func (enc *Encoding) maxRuneLen() int {
+	if enc.presentation {
+		return encodedByteSize + len(vs16)
+	}
+	return encodedByteSize
+}
+
+// encodeRunes writes the runes for src to dst without any line breaks,
+// returning the number of bytes written.
+func (enc *Encoding) encodeRunes(dst, src []byte) int {
+	if enc.presentation {
+		return enc.encodeRunesPresentation(dst, src)
+	}
+	encode(dst, src)
+	if enc.skinToneSafe {
+		remapSkinTones(dst, src)
+	}
+	return EncodedLen(len(src))
+}
+
+// encodeRunesPresentation is encodeRunes with variation selectors. Input is
+// split after each byte encoded as a text default rune, so everything in
+// between can still be handled by the regular encoder.
+func (enc *Encoding) encodeRunesPresentation(dst, src []byte) int {
+	var n int
+	for len(src) > 0 {
+		segmentSize := len(src)
+		for i, b := range src {
+			if isTextDefault(b) {
+				segmentSize = i + 1
+				break
+			}
+		}
+
+		segment := src[:segmentSize]
+		encode(dst[n:], segment)
+		if enc.skinToneSafe {
+			remapSkinTones(dst[n:], segment)
+		}
+		n += EncodedLen(segmentSize)
+		if isTextDefault(segment[segmentSize-1]) {
+			n += copy(dst[n:], vs16)
+		}
+		src = src[segmentSize:]
+	}
+	return n
+}
+
+// isTextDefault reports whether b is encoded as a rune which does not have the
+// Emoji_Presentation property, and is thus rendered as text by default. These
+// are the same in both alphabets.
+func isTextDefault(b byte) bool {
+	return b == 0x00 || b == 0x48 || b == 0x4a
+}
+
+// remapSkinTones rewrites the skin tone modifier runes in dst, the standard
+// encoding of src, to those of the skin tone safe alphabet. Doing this as a
+// separate pass leaves the hot loop in encode untouched.
+func remapSkinTones(dst, src []byte) {
+	for i, b := range src {
+		if b-0x04 < 5 {
+			dst[encodedByteSize*i+2] = 0x93
+			dst[encodedByteSize*i+3] = 0xb7 + (b - 0x04)
+		}
+	}
+}
+
+// encodeTable holds the rune of the standard alphabet for each byte value,
+// as the little endian word of its four bytes of UTF-8, so that encode can
+// write out a whole rune with a single store.
+var encodeTable = func() (t [256]uint32) {
+	for b := range t {
 		/* Rust version:
 		out[4 * i + 0] = 0xf0;
 		out[4 * i + 1] = 0x9f;
@@ -34,20 +334,31 @@ func Encode(dst, src []byte) {
 		// (ch + 55) & 0x3f approximates (ch + 55) % 64
 		out[4 * i + 3] = (ch.wrapping_add(55) & 0x3f).wrapping_add(128);
 		*/
-		dst[0] = fixedByte1
-		dst[1] = fixedByte2
-		dst[2] = byte((uint16(b)+55)/64 + 143)
-		dst[3] = (b+55)%64 + 128
-		// dst[2] = byte(((uint16(b) + 55) >> 6) + 143)
-		// dst[3] = (b+55)&0x3f + 128
-		//
-		// ^^ These bit shifting variations from Rust version don't appear to
-		// impact speed at all when benchmarking here (in go1.14), let's assume
-		// the Go compiler is smart enough to apply them already automatically?
-		// Thus omit for readability.
+		t[b] = fixedByte1 | fixedByte2<<8 | uint32((b+55)/64+143)<<16 | uint32((b+55)%64+128)<<24
+	}
+	return t
+}()
 
-		dst = dst[encodedByteSize:]
-		src = src[1:]
+// encode is the core encoding loop, writing the runes of the standard alphabet
+// for src to dst, as many as will fit.
+func encode(dst, src []byte) {
+	if useAVX2 {
+		n := encodeAVX2(dst, src)
+		dst, src = dst[n*encodedByteSize:], src[n:]
+	}
+
+	n := min(len(dst)/encodedByteSize, len(src))
+	dst = dst[:n*encodedByteSize] // BCE hint!
+	src = src[:n]                 // BCE hint!
+
+	// Two runes to a store while we can.
+	i := 0
+	for ; i+2 <= n; i += 2 {
+		w := uint64(encodeTable[src[i]]) | uint64(encodeTable[src[i+1]])<<32
+		binary.LittleEndian.PutUint64(dst[encodedByteSize*i:], w)
+	}
+	if i < n {
+		binary.LittleEndian.PutUint32(dst[encodedByteSize*i:], encodeTable[src[i]])
 	}
 }
 
@@ -57,87 +368,378 @@ func EncodedLen(n int) int {
 	return n * encodedByteSize
 }
 
+// EncodedLen returns the length in bytes of the encoding of an input buffer of
+// length n using enc, including any line terminators. If enc uses
+// EmojiPresentation, this is the maximum length rather than the exact one.
+func (enc *Encoding) EncodedLen(n int) int {
+	runesLen := n * enc.maxRuneLen()
+	if enc.wrap == 0 {
+		return runesLen
+	}
+	lines := (n + enc.wrap - 1) / enc.wrap
+	return runesLen + lines*len(enc.eol)
+}
+
 // EncodeToString returns the base100 encoding of src.
 func EncodeToString(src []byte) string {
-	buf := make([]byte, EncodedLen(len(src)))
-	Encode(buf, src)
-	return string(buf)
+	return StdEncoding.EncodeToString(src)
+}
+
+// EncodeToString returns the encoding of src using enc.
+func (enc *Encoding) EncodeToString(src []byte) string {
+	buf := make([]byte, enc.EncodedLen(len(src)))
+	n := enc.Encode(buf, src)
+	if n == 0 {
+		return ""
+	}
+	if n < len(buf) {
+		// Sized for the worst case of EmojiPresentation, buf would keep more
+		// than the string needs alive, so copy the encoding out of it.
+		return string(buf[:n])
+	}
+	// buf is not used again, so it can become the string without a copy.
+	return unsafe.String(&buf[0], n)
+}
+
+// AppendEncode appends the base100 encoding of src to dst and returns the
+// extended buffer.
+func AppendEncode(dst, src []byte) []byte {
+	return StdEncoding.AppendEncode(dst, src)
+}
+
+// AppendEncode appends the encoding of src using enc to dst and returns the
+// extended buffer.
+func (enc *Encoding) AppendEncode(dst, src []byte) []byte {
+	n := enc.EncodedLen(len(src))
+	dst = slices.Grow(dst, n)
+	n = enc.Encode(dst[len(dst):][:n], src)
+	return dst[:len(dst)+n]
 }
 
 /* DECODE */
 
 // Decode decodes src using base100. It writes at most DecodedLen(len(src))
-// bytes to dst and returns the number of bytes written.
+// bytes to dst and returns the number of bytes written. If dst is too short,
+// Decode decodes as much as fits and returns ErrShortDst. If src ends with an
+// incomplete rune, Decode decodes everything before it and returns a
+// TruncatedInputError.
+//
+// Decode does not verify that src is well-formed base100, and will happily
+// decode arbitrary input into garbage. Use DecodeStrict for untrusted input.
 //
-// New line characters (\r and \n) should be stripped beforehand.
+// Whitespace characters (\r, \n, space and tab) in between runes are ignored.
 func Decode(dst, src []byte) (n int, err error) {
+	return StdEncoding.Decode(dst, src)
+}
+
+// DecodeStrict is like Decode, but verifies that every 4 byte group of src is
+// a valid base100 rune, returning a CorruptInputError locating the first one
+// that is not.
+func DecodeStrict(dst, src []byte) (n int, err error) {
+	return strictEncoding.Decode(dst, src)
+}
+
+// Decode decodes src using the encoding enc. It writes at most
+// DecodedLen(len(src)) bytes to dst and returns the number of bytes written.
+// Errors are reported the same way as by the package level Decode.
+func (enc *Encoding) Decode(dst, src []byte) (n int, err error) {
 	// if len(src)%4 != 0 {
 	// 	return 0, errors.New("invalid length")
 	// }
-	/* we no longer need above check, as any trailing bytes will be sliced off
-	anyhow during BCE hinting */
+	/* we no longer need above check up front, as decodeChunk leaves any
+	incomplete trailing rune unconsumed, and it is reported after decoding */
+	n, nsrc, nskip, err := enc.decodeChunk(dst, src)
+	if err != nil || nsrc == len(src) {
+		return n, err
+	}
 
+	// decodeChunk stops short either when it runs out of room in dst, or when
+	// the remaining input is too short to contain a rune.
+	//
+	// In the standard library implmentation of base64, if len(dst) <
+	// DecodedLen(len(dst))), the method will panic. However, it seems like we
+	// can be a bit more graceful here.
+	rest := src[nsrc:]
+	if len(rest) >= encodedByteSize {
+		return n, ErrShortDst
+	}
+	return n, enc.trailingError(rest, int64(nsrc), int64(n+nskip))
+}
+
+// decodeChunk is the workhorse behind both Decode and the stream decoder. It
+// decodes runes from src into dst until it runs out of either room in dst or
+// complete runes in src, skipping over any whitespace (and variation selectors)
+// along the way if enabled.
+//
+// It returns the number of bytes written to dst, the number of bytes consumed
+// from src, and how many runes of those consumed were skipped over. Any
+// incomplete rune at the end of src is left unconsumed for the caller to deal
+// with, since in a stream the rest of it may simply not have arrived yet.
+func (enc *Encoding) decodeChunk(dst, src []byte) (n, nsrc, nskip int, err error) {
+	for {
+		// Spend as much time as possible in the fast path, which only handles
+		// back to back runes and bails out on anything else.
+		var k int
+		switch {
+		case enc.skinToneSafe:
+			k = decodeRunSkinToneSafe(dst[n:], src[nsrc:], enc.strict)
+		case enc.strict:
+			k = decodeRunStrict(dst[n:], src[nsrc:])
+		default:
+			k = decodeRun(dst[n:], src[nsrc:])
+		}
+		n += k
+		nsrc += k * encodedByteSize
+
+		// The fast path stopped, figure out why.
+		rest := src[nsrc:]
+		if len(rest) == 0 {
+			return n, nsrc, nskip, nil
+		}
+		if skip := enc.skipLen(rest); skip > 0 {
+			nsrc += skip
+			nskip++
+			continue
+		}
+		switch {
+		case n == len(dst) || len(rest) < encodedByteSize:
+			return n, nsrc, nskip, nil
+		case enc.strict:
+			return n, nsrc, nskip, CorruptInputError{Offset: int64(nsrc), Index: int64(n + nskip)}
+		default:
+			// Not whitespace, yet not a rune either. Without validation, decode
+			// it regardless just as the Rust version would.
+			dst[n] = (rest[2]-143)*64 + rest[3] - 128 - 55
+			n++
+			nsrc += encodedByteSize
+		}
+	}
+}
+
+// skipLen returns the length of the character at the start of b if it is one
+// which enc skips over when decoding, or zero otherwise. A character which is
+// cut short by the end of b is not recognized, so that a stream decoder will
+// carry it over to be completed by its next read.
+func (enc *Encoding) skipLen(b []byte) int {
+	switch {
+	case enc.whitespace && isSpace(b[0]):
+		return 1
+	case b[0] != vs16[0] && b[0] != zwj[0]:
+		return 0 // quick rejection of anything else
+	case enc.skipsVS16() && bytes.HasPrefix(b, []byte(vs16)):
+		return len(vs16)
+	case enc.formatChars && (bytes.HasPrefix(b, []byte(vs15)) || bytes.HasPrefix(b, []byte(zwj))):
+		return len(vs15) // all the same length
+	}
+	return 0
+}
+
+// isSpace reports whether b is a whitespace character ignored by the decoder.
+func isSpace(b byte) bool {
+	return b == '\n' || b == '\r' || b == ' ' || b == '\t'
+}
+
+// decodeBlock is the number of runes the fast path decoders process between
+// checks for anything unexpected in the input.
+const decodeBlock = 16
+
+// decodeRun decodes runes from src into dst for as long as each one starts with
+// the expected first byte, returning the number of runes decoded. Nothing else
+// about the runes is verified.
+//
+// Input is processed in blocks, where the first bytes are accumulated and
+// checked once for the entire block rather than branching for every rune, so
+// that the check for whitespace costs next to nothing when there is none. A
+// block with anything unexpected in it is then redone a rune at a time, so dst
+// may be written past the returned count.
+//
+// Within a block, runes are decoded a group of eight at a time, from four
+// words of input, see decodePair.
+func decodeRun(dst, src []byte) int {
 	/* Rust version:
 	for (i, chunk) in buf.chunks(4).enumerate() {
 	    out[i] = ((chunk[2].wrapping_sub(143)).wrapping_mul(64))
 	        .wrapping_add(chunk[3].wrapping_sub(128)).wrapping_sub(55)
 	}
 	*/
+	max := min(len(dst), len(src)/encodedByteSize)
+	dst = dst[:max]                 // BCE hint!
+	src = src[:max*encodedByteSize] // BCE hint!
 
-	// Wacky compiler shenanigans for "undetected" bounds check elimination.
-	//
-	// When building with `go build -gcflags="-d=ssa/check_bce/debug=1"` this
-	// reports BCE has not occured for src, e.g. it sees `IsInBounds` checks
-	// occuring in the hoot loop, BUT the benchmarks nearly double from 550MB/s
-	// to 1GB/s, so this is clearly doing BCE despite gcflags not reporting it?
-	// Hence the "undetected" comment above. This lack of reporting may actually
-	// be a bug in the Go compiler toolchain, we should check in next patch
-	// version and file a bug if so.
-	max := len(src) / encodedByteSize
-	const employBCE = true
-	if employBCE { // ^^ hard coded enabled above
-		if len(dst) >= max && len(src) >= max*encodedByteSize {
-			dst = dst[:max]                 // BCE hint!
-			src = src[:max*encodedByteSize] // BCE hint!
-		} else {
-			// In the standard library implmentation of base64, if len(dst) <
-			// DecodedLen(len(dst))), the method will panic. However, it seems
-			// like we can be a bit more graceful here.
-			return n, errors.New("insufficient slice size")
+	i := 0
+	if useAVX2 {
+		i = decodeAVX2(dst, src, false)
+	}
+	for ; i+decodeBlock <= max; i += decodeBlock {
+		var bad uint64
+		for j := i; j < i+decodeBlock; j += groupSize {
+			g := src[encodedByteSize*j:][:groupSize*encodedByteSize] // BCE hint!
+			w0 := binary.LittleEndian.Uint64(g[0:])
+			w1 := binary.LittleEndian.Uint64(g[8:])
+			w2 := binary.LittleEndian.Uint64(g[16:])
+			w3 := binary.LittleEndian.Uint64(g[24:])
+			bad |= (w0 ^ pairFirstBytes) | (w1 ^ pairFirstBytes) | (w2 ^ pairFirstBytes) | (w3 ^ pairFirstBytes)
+			out := packPair(w0) | packPair(w1)<<16 | packPair(w2)<<32 | packPair(w3)<<48
+			binary.LittleEndian.PutUint64(dst[j:], out)
+		}
+		if bad&pairLanes != 0 {
+			break
 		}
 	}
-
-	for i := range max {
+	for ; i < max; i++ {
 		offset := encodedByteSize * i
-		// Optionally validate the prefix bytes are as expected on every rune.
-		// This is quite wasteful for performance, and the Rust version of the
-		// algorithm does not perform it, so leave it disabled here for now.
-		const validate = false
-		if validate { // ^^ hard coded disabled above
-			pos1 := src[offset+0]
-			pos2 := src[offset+1]
-			if pos1 != fixedByte1 || pos2 != fixedByte2 {
-				return n, errors.New("invalid encoding")
-			}
+		if src[offset] != fixedByte1 {
+			break
 		}
-
 		pos3 := src[offset+2]
 		pos4 := src[offset+3]
 		dst[i] = (pos3-143)*64 + pos4 - 128 - 55
-		n++
 	}
+	return i
+}
 
-	// Alternate version of the loop that employs via a different BCE technique
-	// similar to Encode(), however it ends up being slower when benchmarked:
-	//
-	// 		for len(dst) >= 1 && len(src) >= 4 {
-	// 			chunk := src[:4]
-	// 			dst[0] = (chunk[2]-143)*64 + chunk[3] - 128 - 55
-	// 			dst = dst[1:]
-	// 			src = src[4:]
-	// 		}
+// decodeRunStrict is like decodeRun, but stops at the first invalid rune
+// rather than only the ones not starting with the expected byte.
+//
+// Validation gets its own copy of the loop rather than a branch inside of the
+// hot one, so that the default non-validating path is left untouched. See
+// decodeRune for how the validation itself works, here it is done on all the
+// bytes of a group of runes at once.
+func decodeRunStrict(dst, src []byte) int {
+	max := min(len(dst), len(src)/encodedByteSize)
+	dst = dst[:max]                 // BCE hint!
+	src = src[:max*encodedByteSize] // BCE hint!
 
-	return n, nil
+	i := 0
+	if useAVX2 {
+		i = decodeAVX2(dst, src, true)
+	}
+	for ; i+decodeBlock <= max; i += decodeBlock {
+		var bad uint64
+		for j := i; j < i+decodeBlock; j += groupSize {
+			g := src[encodedByteSize*j:][:groupSize*encodedByteSize] // BCE hint!
+			w0 := binary.LittleEndian.Uint64(g[0:])
+			w1 := binary.LittleEndian.Uint64(g[8:])
+			w2 := binary.LittleEndian.Uint64(g[16:])
+			w3 := binary.LittleEndian.Uint64(g[24:])
+			bad |= pairBad(w0) | pairBad(w1) | pairBad(w2) | pairBad(w3)
+			out := packPair(w0) | packPair(w1)<<16 | packPair(w2)<<32 | packPair(w3)<<48
+			binary.LittleEndian.PutUint64(dst[j:], out)
+		}
+		if bad != 0 {
+			break
+		}
+	}
+	for ; i < max; i++ {
+		offset := encodedByteSize * i
+		b, ok := decodeRune(src[offset+0], src[offset+1], src[offset+2], src[offset+3])
+		if !ok {
+			break
+		}
+		dst[i] = b
+	}
+	return i
+}
+
+// The fast path decoders use SWAR (SIMD within a register) arithmetic, loading
+// a pair of runes at a time into a 64 bit word, where each is decoded in its
+// own 32 bit lane. A lane has ample room for the intermediate value of a rune
+// (see decodeRune), which is biased so as to never go negative and borrow from
+// the lane above.
+const (
+	groupSize = 8 // runes decoded at a time within a block, two per word
+
+	pairLanes      = 0x000000ff_000000ff // low byte of each lane
+	pairBias       = (1<<16 - (143<<6 + 128 + 55)) * (1 | 1<<32)
+	pairFirstBytes = fixedByte1 * (1 | 1<<32)
+)
+
+// decodePair returns the decoded values of the pair of runes in w, biased by
+// 1<<16, in their lanes. A valid rune therefore has a lane of 1<<16 plus the
+// decoded byte; for any rune, the low byte of its lane is what decodeRun would
+// decode it as.
+func decodePair(w uint64) uint64 {
+	return (w>>16&pairLanes)<<6 + w>>24&pairLanes + pairBias
+}
+
+// pairBad returns nonzero if either rune of the pair in w is invalid. It is the
+// check of decodeRune, done on both lanes of a pair at once.
+func pairBad(w uint64) uint64 {
+	v := decodePair(w)
+	return (w&0xc000ffff_c000ffff ^ 0x80009ff0_80009ff0) | (v&^pairLanes ^ 1<<16*(1|1<<32))
+}
+
+// packPair returns the decoded bytes of the pair of runes in w, next to each
+// other in the low 16 bits.
+func packPair(w uint64) uint64 {
+	v := decodePair(w) & pairLanes
+	return (v | v>>24) & 0xffff
+}
+
+// decodeRunSkinToneSafe is the equivalent of decodeRun and decodeRunStrict for
+// the skin tone safe alphabet. Being opt-in, it favors simplicity over speed.
+func decodeRunSkinToneSafe(dst, src []byte, strict bool) int {
+	max := min(len(dst), len(src)/encodedByteSize)
+	dst = dst[:max]                 // BCE hint!
+	src = src[:max*encodedByteSize] // BCE hint!
+
+	for i := range max {
+		offset := encodedByteSize * i
+		pos1, pos2, pos3, pos4 := src[offset+0], src[offset+1], src[offset+2], src[offset+3]
+
+		// The replacement runes immediately follow the standard alphabet, so
+		// they come out of the usual arithmetic as the values 256-260.
+		v := uint(pos3)<<6 + uint(pos4) - (143<<6 + 128 + 55)
+		if v-256 < 5 {
+			v -= 256 - 0x04
+		}
+
+		if strict {
+			if pos1 != fixedByte1 || pos2 != fixedByte2 || pos4&0xc0 != 0x80 || v >= 256 {
+				return i
+			}
+		} else if pos1 != fixedByte1 {
+			return i
+		}
+		dst[i] = byte(v)
+	}
+	return max
+}
+
+// decodeRune decodes a single base100 rune from its four UTF-8 bytes,
+// reporting whether they form a valid base100 rune.
+//
+// The valid runes are U+1F3F7 through U+1F4F6, so beyond the two fixed prefix
+// bytes, pos4 must be a UTF-8 continuation byte and the combined value of the
+// last two bytes must fall within the 256 values of the table. Computing that
+// value as an unsigned int lets a single comparison catch underflow as well,
+// and its low byte is exactly what the non-validating decoder produces.
+func decodeRune(pos1, pos2, pos3, pos4 byte) (byte, bool) {
+	v := uint(pos3)<<6 + uint(pos4) - (143<<6 + 128 + 55)
+	ok := pos1 == fixedByte1 && pos2 == fixedByte2 && pos4&0xc0 == 0x80 && v < 256
+	return byte(v), ok
+}
+
+// Valid reports whether src is entirely composed of valid base100 runes and
+// whitespace, i.e. whether DecodeStrict would succeed on it.
+func Valid(src []byte) bool {
+	return StdEncoding.Valid(src)
+}
+
+// Valid reports whether src is valid input for enc, i.e. whether decoding it
+// with enc.Strict() would succeed, regardless of whether enc is strict itself.
+func (enc *Encoding) Valid(src []byte) bool {
+	strict := *enc
+	strict.strict = true
+	var buf [bufferSize]byte // scratch space, the output is discarded
+	for len(src) > 0 {
+		_, nsrc, _, err := strict.decodeChunk(buf[:], src)
+		if err != nil || nsrc == 0 {
+			return false // invalid rune, or incomplete one at the end
+		}
+		src = src[nsrc:]
+	}
+	return true
 }
 
 // DecodedLen returns the maximum length in bytes of the decoded data
@@ -147,31 +749,168 @@ func DecodedLen(n int) int {
 	return n / encodedByteSize
 }
 
+// DecodedLen returns the maximum length in bytes of the decoded data
+// corresponding to n bytes of data encoded with enc.
+func (enc *Encoding) DecodedLen(n int) int {
+	return DecodedLen(n)
+}
+
 // DecodeString returns the bytes represented by the base100 string s.
 func DecodeString(s string) ([]byte, error) {
-	src := []byte(s)
-	buf := make([]byte, DecodedLen(len(src)))
-	_, err := Decode(buf, src)
-	return buf, err
+	return StdEncoding.DecodeString(s)
+}
+
+// DecodeStringStrict is like DecodeString, but validates s the same way as
+// DecodeStrict.
+func DecodeStringStrict(s string) ([]byte, error) {
+	return strictEncoding.DecodeString(s)
+}
+
+// DecodeString returns the bytes represented by the string s encoded with enc.
+func (enc *Encoding) DecodeString(s string) ([]byte, error) {
+	// Decode only reads from src, so s can be used without a copy.
+	src := unsafe.Slice(unsafe.StringData(s), len(s))
+	buf := make([]byte, enc.DecodedLen(len(src)))
+	n, err := enc.Decode(buf, src)
+	return buf[:n], err
+}
+
+// AppendDecode appends the base100 decoding of src to dst and returns the
+// extended buffer. If the input is malformed, it returns the partially decoded
+// src and an error.
+func AppendDecode(dst, src []byte) ([]byte, error) {
+	return StdEncoding.AppendDecode(dst, src)
+}
+
+// AppendDecode appends the decoding of src using enc to dst and returns the
+// extended buffer. If the input is malformed, it returns the partially decoded
+// src and an error.
+func (enc *Encoding) AppendDecode(dst, src []byte) ([]byte, error) {
+	n := enc.DecodedLen(len(src))
+	dst = slices.Grow(dst, n)
+	n, err := enc.Decode(dst[len(dst):][:n], src)
+	return dst[:len(dst)+n], err
 }
 
 /* ENCODER */
 
 // NewEncoder returns a new base100 stream encoder. Data written to the returned
 // writer will be encoded using base100 and then written to w.
-func NewEncoder(w io.Writer) io.Writer {
-	return &encoder{w: w}
+func NewEncoder(w io.Writer) *Encoder {
+	return StdEncoding.NewEncoder(w)
+}
+
+// NewEncoder returns a new stream encoder using enc. Data written to the
+// returned writer will be encoded and then written to w.
+//
+// If enc wraps lines, the caller must Close the returned encoder when finished
+// writing to terminate the final partial line, if any. Otherwise Close is a
+// no-op, but calling it regardless is good practice.
+func (enc *Encoding) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{enc: enc, w: w}
+}
+
+// NewWrappingEncoder returns a new base100 stream encoder which breaks its
+// output into lines of width runes (not bytes, since each rune is 4 bytes of
+// UTF-8) terminated by eol, much like GNU base64 -w. A width of zero disables
+// wrapping, making it equivalent to NewEncoder. NewWrappingEncoder panics if
+// width is negative.
+//
+// When finished writing, the caller must Close the returned encoder to
+// terminate the final partial line, if any.
+func NewWrappingEncoder(w io.Writer, width int, eol LineEnding) *Encoder {
+	return StdEncoding.WithWrap(width, eol).NewEncoder(w)
 }
 
 const bufferSize = 1024
 
-type encoder struct {
-	w   io.Writer
-	err error
-	out [bufferSize]byte // output buffer
+// copyBufferSize is the size of the larger buffers used by ReadFrom and
+// WriteTo, matching those of io.Copy. They are allocated on first use, and
+// kept across calls to Reset, so that a reused encoder or decoder does not
+// allocate them again.
+const copyBufferSize = 32 * 1024
+
+// An Encoder is a base100 stream encoder, as returned by NewEncoder. Its
+// zero value is not usable.
+//
+// Once a write to the underlying writer fails, or comes up short without an
+// error, in which case the error is io.ErrShortWrite, the error is sticky:
+// every later call to Write returns it, as do Close and Err. The encoder holds
+// on to the unwritten remainder of a rune cut short by the failed write, and
+// ReadFrom to the input it has read but not yet encoded, so that the output can
+// be picked up again where it left off once the underlying writer has
+// recovered. See Flush.
+type Encoder struct {
+	w       io.Writer
+	err     error
+	enc     *Encoding
+	col     int              // runes written to the current line when wrapping
+	inOff   int64            // bytes of input consumed
+	outOff  int64            // bytes of output written to w
+	pending []byte           // output held back by a failed write, within out
+	unread  []byte           // input held back by a failed ReadFrom, within copyBuf
+	out     [bufferSize]byte // output buffer
+	copyBuf []byte           // buffers for ReadFrom, see copyBufferSize
+}
+
+// Reset discards the encoder's state and makes it equivalent to the result of
+// NewEncoder called with w, using the same Encoding. This permits reusing an
+// Encoder rather than allocating a new one. A partial line is not terminated,
+// so call Close before Reset if needed.
+func (e *Encoder) Reset(w io.Writer) {
+	*e = Encoder{enc: e.enc, w: w, copyBuf: e.copyBuf}
 }
 
-func (e *encoder) Write(p []byte) (n int, err error) {
+// InputOffset returns the number of bytes written to the encoder so far.
+func (e *Encoder) InputOffset() int64 {
+	return e.inOff
+}
+
+// OutputOffset returns the number of encoded bytes written to the underlying
+// writer so far.
+func (e *Encoder) OutputOffset() int64 {
+	return e.outOff
+}
+
+// Err returns the error which stopped the encoder, if any.
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+// Flush retries writing any output held back by a failed write, and encodes
+// any input held back by a failed ReadFrom. If that succeeds, or there was
+// nothing held back, the sticky error is cleared, and the caller may carry on
+// writing from p[n:], where p and n are those of the failed Write, or call
+// ReadFrom again. Otherwise Flush returns the new error.
+//
+// The encoder holds nothing back between successful writes, so Flush is not
+// needed in the normal course of writing.
+func (e *Encoder) Flush() error {
+	if len(e.pending) > 0 {
+		written, err := e.w.Write(e.pending)
+		e.outOff += int64(written)
+		e.pending = e.pending[written:]
+		if err == nil && len(e.pending) > 0 {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			e.err = err
+			return err
+		}
+	}
+	e.err = nil
+	if len(e.unread) > 0 {
+		written, err := e.write(e.unread, e.copyBuf[copyBufferSize/encodedByteSize:])
+		e.unread = e.unread[written:]
+		return err
+	}
+	return nil
+}
+
+// Write encodes p and writes the result to the underlying writer. On error, n
+// is the number of bytes from p whose encoding has been written in full, or
+// else is being held to be completed by Flush.
+func (e *Encoder) Write(p []byte) (n int, err error) {
 	/* (io.Writer).Write() notes:
 
 	Write writes len(p) bytes from p to the underlying data stream. It returns
@@ -182,37 +921,330 @@ func (e *encoder) Write(p []byte) (n int, err error) {
 
 	Implementations must not retain p.
 	*/
-	for len(p) > 0 && e.err == nil {
-		chunkSize := min(len(p), bufferSize/encodedByteSize)
+	return e.write(p, e.out[:])
+}
 
-		chunk := p[:chunkSize]
-		Encode(e.out[:], chunk)
-		numBytesEncoded := EncodedLen(len(chunk))
+// write encodes p to the underlying writer, using out as the output buffer.
+func (e *Encoder) write(p, out []byte) (n int, err error) {
+	for len(p) > 0 && e.err == nil {
+		col := e.col
+		nbuf, consumed, newCol := e.enc.encodeLines(out, p, col)
 
-		var written int
-		written, e.err = e.w.Write(e.out[:numBytesEncoded])
+		written, werr := e.w.Write(out[:nbuf])
+		e.outOff += int64(written)
+		if werr == nil && written < nbuf {
+			werr = io.ErrShortWrite
+		}
+		if werr != nil {
+			// Count the input whose encoding went out in full or in part,
+			// holding on to the rest of the part for Flush.
+			var end int
+			consumed, end, newCol = e.enc.splitWritten(p[:consumed], col, written)
+			e.pending = e.out[:copy(e.out[:], out[written:end])]
+			e.err = werr
+		}
 
-		n += written / encodedByteSize
-		p = p[chunkSize:]
+		e.col = newCol
+		n += consumed
+		p = p[consumed:]
 	}
+	e.inOff += int64(n)
 	return n, e.err
 }
 
-/* DECODER */
+// splitWritten works out how much of src is accounted for by the first written
+// bytes of its encoding, as laid out by encodeLines starting at column col. It
+// returns the number of bytes of src whose encoding was written in full or in
+// part, the offset in the encoding of the end of the last of those (including
+// a line ending which follows it), and the column after it.
+func (enc *Encoding) splitWritten(src []byte, col, written int) (nsrc, end, newCol int) {
+	for nsrc < len(src) && end < written {
+		end += encodedByteSize
+		if enc.presentation && isTextDefault(src[nsrc]) {
+			end += len(vs16)
+		}
+		nsrc++
+
+		if enc.wrap == 0 {
+			continue
+		}
+		if col++; col == enc.wrap {
+			end += len(enc.eol)
+			col = 0
+		}
+	}
+	return nsrc, end, col
+}
+
+// encodeLines encodes as much of src into dst as will fit, breaking lines if
+// enc wraps them, where col is the number of runes already on the current
+// line. It returns the number of bytes written to dst and consumed from src,
+// along with the updated col.
+//
+// As many lines (or parts of lines) are packed into dst as possible, so that
+// narrow widths don't turn into lots of tiny writes for the stream encoders.
+func (enc *Encoding) encodeLines(dst, src []byte, col int) (ndst, nsrc, newCol int) {
+	for nsrc < len(src) {
+		room := (len(dst) - ndst - len(enc.eol)) / enc.maxRuneLen()
+		if room <= 0 {
+			break
+		}
+		chunkSize := min(len(src)-nsrc, room)
+		if enc.wrap > 0 {
+			chunkSize = min(chunkSize, enc.wrap-col)
+		}
+		ndst += enc.encodeRunes(dst[ndst:], src[nsrc:nsrc+chunkSize])
+		nsrc += chunkSize
 
-// NewDecoder constructs a new base100 stream decoder.
-func NewDecoder(r io.Reader) io.Reader {
-	return &decoder{r: r}
+		if enc.wrap == 0 {
+			continue
+		}
+		if col += chunkSize; col == enc.wrap {
+			ndst += copy(dst[ndst:], enc.eol)
+			col = 0
+		}
+	}
+	return ndst, nsrc, col
 }
 
-type decoder struct {
+// Close terminates the final line of output if wrapping is enabled and the
+// line is not already terminated. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.err == nil && e.col > 0 {
+		written, err := io.WriteString(e.w, e.enc.eol)
+		e.outOff += int64(written)
+		if err == nil && written < len(e.enc.eol) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			e.pending = e.out[:copy(e.out[:], e.enc.eol[written:])]
+			e.err = err
+		}
+		e.col = 0
+	}
+	return e.err
+}
+
+// ReadFrom implements io.ReaderFrom. It reads data from r until EOF or error,
+// encoding it and writing the result to the underlying writer, with buffers
+// much larger than those used by Write. The return value n is the number of
+// bytes read from r. Any error except EOF encountered during the read is also
+// returned.
+//
+// If writing fails, the encoder holds on to whatever it has read from r but not
+// yet encoded, which is counted in n, for Flush to write once the underlying
+// writer has recovered.
+//
+// As with Write, the caller must still Close the encoder when finished to
+// terminate the final partial line, if any.
+func (e *Encoder) ReadFrom(r io.Reader) (n int64, err error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	if e.copyBuf == nil {
+		e.copyBuf = make([]byte, copyBufferSize/encodedByteSize+copyBufferSize)
+	}
+	in, out := e.copyBuf[:copyBufferSize/encodedByteSize], e.copyBuf[copyBufferSize/encodedByteSize:]
+	for {
+		numRead, rerr := readSome(r, in)
+		if numRead > 0 {
+			n += int64(numRead)
+			numWritten, werr := e.write(in[:numRead], out)
+			if werr != nil {
+				e.unread = in[numWritten:numRead]
+				return n, werr
+			}
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+// maxConsecutiveEmptyReads is how many times in a row an underlying reader may
+// return no data and no error before it is given up on, as bufio does.
+const maxConsecutiveEmptyReads = 100
+
+// readSome reads from r into b, which must not be empty, retrying reads which
+// return no data and no error. If r never returns anything, the error is
+// io.ErrNoProgress.
+func readSome(r io.Reader, b []byte) (n int, err error) {
+	for range maxConsecutiveEmptyReads {
+		n, err = r.Read(b)
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, io.ErrNoProgress
+}
+
+/* ENCODING READER */
+
+// NewEncodingReader returns a reader which yields the base100 encoding of the
+// data read from r. It is the pull based counterpart to NewEncoder, for when
+// an io.Reader of encoded data is needed, such as for an HTTP request body.
+func NewEncodingReader(r io.Reader) *EncodingReader {
+	return StdEncoding.NewEncodingReader(r)
+}
+
+// NewEncodingReader returns a reader which yields the encoding using enc of the
+// data read from r. If enc wraps lines, the final line is terminated as soon as
+// r is exhausted.
+func (enc *Encoding) NewEncodingReader(r io.Reader) *EncodingReader {
+	return &EncodingReader{enc: enc, r: r}
+}
+
+// An EncodingReader yields the encoding of the data read from an underlying
+// reader. See NewEncodingReader.
+type EncodingReader struct {
+	enc *Encoding
 	r   io.Reader
-	err error
-	in  []byte           // input buffer (encoded form)
-	arr [bufferSize]byte // backing array for in
+	err error                              // sticky error from r
+	col int                                // runes written to the current line when wrapping
+	in  []byte                             // raw input not yet encoded
+	out []byte                             // encoded output not yet read
+	raw [bufferSize]byte                   // backing array for in
+	arr [bufferSize * encodedByteSize]byte // backing array for out
+}
+
+// Read reads the encoding of the underlying data into p.
+func (r *EncodingReader) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(r.out) == 0 {
+		// only expose errors once everything read before them is delivered
+		if r.err != nil && len(r.in) == 0 {
+			return 0, r.err
+		}
+		r.fill()
+	}
+	n = copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// fill refills the output buffer, reading more input first if need be.
+func (r *EncodingReader) fill() {
+	if len(r.in) == 0 && r.err == nil {
+		var numRead int
+		numRead, r.err = readSome(r.r, r.raw[:])
+		r.in = r.raw[:numRead]
+	}
+
+	ndst, nsrc, col := r.enc.encodeLines(r.arr[:], r.in, r.col)
+	r.out, r.in, r.col = r.arr[:ndst], r.in[nsrc:], col
+
+	// terminate the final line, once everything else has been encoded
+	if r.err == io.EOF && len(r.in) == 0 && r.col > 0 {
+		r.out = append(r.out, r.enc.eol...)
+		r.col = 0
+	}
+}
+
+// Len returns the number of bytes of the unread portion of the encoding, or -1
+// if it is not known.
+//
+// The length is only known if the underlying reader has a Len method reporting
+// its own unread portion, as do *bytes.Buffer, *bytes.Reader and
+// *strings.Reader, and the encoding does not use EmojiPresentation, which makes
+// the length dependent on the content. This is useful to set the Content-Length
+// of an HTTP request, for example.
+func (r *EncodingReader) Len() int {
+	lr, ok := r.r.(interface{ Len() int })
+	if !ok || r.enc.presentation {
+		return -1
+	}
+
+	// everything not yet encoded, whether still in r or our buffer
+	remaining := len(r.in)
+	if r.err == nil {
+		remaining += lr.Len()
+	}
+
+	n := len(r.out) + EncodedLen(remaining)
+	if r.enc.wrap > 0 {
+		// one terminator for each line completed from here on, including a
+		// final partial one
+		n += (r.col + remaining + r.enc.wrap - 1) / r.enc.wrap * len(r.enc.eol)
+	}
+	return n
 }
 
-func (d *decoder) Read(p []byte) (n int, err error) {
+/* DECODER */
+
+// NewDecoder constructs a new base100 stream decoder. Like Decode, it ignores
+// any whitespace in the input.
+func NewDecoder(r io.Reader) *Decoder {
+	return StdEncoding.NewDecoder(r)
+}
+
+// NewStrictDecoder constructs a new base100 stream decoder which validates its
+// input the same way as DecodeStrict.
+func NewStrictDecoder(r io.Reader) *Decoder {
+	return strictEncoding.NewDecoder(r)
+}
+
+// NewDecoder constructs a new stream decoder using enc.
+func (enc *Encoding) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{enc: enc, r: r}
+}
+
+// A Decoder is a base100 stream decoder, as returned by NewDecoder. Its zero
+// value is not usable.
+//
+// Once the decoder encounters an error, be it corrupt input, one from the
+// underlying reader, or one from the writer passed to WriteTo, the error is
+// sticky: every later call to Read returns it, as does Err.
+type Decoder struct {
+	enc     *Encoding
+	r       io.Reader
+	err     error
+	off     int64            // input offset of in[0], for error reporting
+	index   int64            // input rune index of in[0], for error reporting
+	outOff  int64            // bytes of output returned by Read
+	in      []byte           // input buffer (encoded form)
+	arr     [bufferSize]byte // backing array for in
+	copyBuf []byte           // buffers for WriteTo, see copyBufferSize
+}
+
+// Reset discards the decoder's state, including any buffered input, and makes
+// it equivalent to the result of NewDecoder called with r, using the same
+// Encoding. This permits reusing a Decoder rather than allocating a new one.
+func (d *Decoder) Reset(r io.Reader) {
+	d.r, d.err = r, nil
+	d.off, d.index, d.outOff = 0, 0, 0
+	d.in = nil
+}
+
+// InputOffset returns the number of bytes of encoded input consumed so far.
+// Input which has been read from the underlying reader but is still buffered,
+// waiting to be decoded, is not counted. After a CorruptInputError, it is the
+// offset of the error.
+func (d *Decoder) InputOffset() int64 {
+	return d.off
+}
+
+// OutputOffset returns the number of decoded bytes returned by Read so far.
+func (d *Decoder) OutputOffset() int64 {
+	return d.outOff
+}
+
+// Err returns the error which stopped the decoder, if any. Reaching the end of
+// well formed input is not an error, so Err returns nil rather than io.EOF.
+// Nor does Err report an error from the underlying reader until Read has
+// returned everything decoded before it.
+func (d *Decoder) Err() error {
+	if d.err == io.EOF || len(d.in) > 0 {
+		return nil
+	}
+	return d.err
+}
+
+func (d *Decoder) Read(p []byte) (n int, err error) {
 	/* (io.Reader).Read() notes:
 
 	Reader is the interface that wraps the basic Read method.
@@ -246,75 +1278,245 @@ func (d *decoder) Read(p []byte) (n int, err error) {
 	// 	- https://golang.org/src/encoding/base64/base64.go
 	// 	- https://golang.org/src/encoding/hex/hex.go
 
-	// assume \r \n stripped via stripper...
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for {
+		// Decode whatever we can from the internal buffer. If it holds nothing
+		// but whitespace or an incomplete rune, n will be zero and we go around
+		// again for more input, rather than returning 0, nil.
+		if len(d.in) > 0 {
+			n, nsrc, nskip, err := d.enc.decodeChunk(p, d.in)
 
-	// Fill internal buffer with sufficient bytes to decode
-	if len(d.in) < encodedByteSize && d.err == nil {
-		var numCopy, numRead int
-		numCopy = copy(d.arr[:], d.in)             // Copies any remainder bytes [0-3] from before into beginning of backing array
-		numRead, d.err = d.r.Read(d.arr[numCopy:]) // read from internal reader with slice of rest of remaining internal buffer
-		d.in = d.arr[:numCopy+numRead]             // reset in to resliced arr containing all data
+			// if decode error; discard input remainder & bubble up error, with
+			// the position made relative to the start of the stream
+			if err != nil {
+				d.in, d.err = nil, relocate(err, d.off, d.index)
+				d.off += int64(nsrc)
+				d.index += int64(n + nskip)
+				d.outOff += int64(n)
+				return n, d.err
+			}
 
-		// handle case: we got an EOF but the bytes we have in our internal
-		// buffer are not a proper multiple of the encodedByteSize.
-		if d.err == io.EOF && len(d.in)%encodedByteSize != 0 {
-			// TODO: hex.go actually checks to see if there is an invalid
-			// encoding byte first, should we do something similar?
-			d.err = io.ErrUnexpectedEOF
+			d.in = d.in[nsrc:] // reslice in to remainder
+			d.off += int64(nsrc)
+			d.index += int64(n + nskip)
+			d.outOff += int64(n)
+			if n > 0 {
+				return n, nil
+			}
+		}
+
+		// only expose errors when buffer fully consumed
+		if d.err != nil {
+			// handle case: we got an EOF but the bytes we have left in our
+			// internal buffer are not a complete rune.
+			if d.err == io.EOF && len(d.in) > 0 {
+				d.err = d.enc.trailingError(d.in, d.off, d.index)
+			}
+			d.in = nil // an incomplete rune can never be completed now
+			return 0, d.err
+		}
+
+		// A large p is better used to read into directly, rather than going
+		// through the internal buffer a little at a time.
+		if len(p) >= largeReadSize {
+			if n, err := d.readInPlace(p); n > 0 || err != nil {
+				return n, err
+			}
+			continue
 		}
-	}
 
-	if numDecodedBytesAvail := len(d.in) / encodedByteSize; len(p) > numDecodedBytesAvail {
-		p = p[:numDecodedBytesAvail] // reslice p to be only size needed...(why?)
+		// Refill internal buffer. Since decodeChunk consumes everything up to
+		// an incomplete rune at the end, only 0-3 remainder bytes carry over.
+		var numRead int
+		numCopy := copy(d.arr[:], d.in)                 // Copies any remainder bytes [0-3] from before into beginning of backing array
+		numRead, d.err = readSome(d.r, d.arr[numCopy:]) // read from internal reader with slice of rest of remaining internal buffer
+		d.in = d.arr[:numCopy+numRead]                  // reset in to resliced arr containing all data
 	}
+}
 
-	numDecodedBytes, err := Decode(p, d.in[:len(p)*encodedByteSize]) // decode into p
-	d.in = d.in[encodedByteSize*numDecodedBytes:]                    // reslice in to remainder
+const (
+	// largeReadSize is the size of p from which Read decodes in place.
+	largeReadSize = 4 * bufferSize
+
+	// inPlaceGap is how far ahead of the decoded output in p readInPlace puts
+	// the encoded input. Decoding is in order and shrinks the data 4:1, so the
+	// output never catches up with input yet to be read, but the fast path
+	// kernels write a whole block of output before finding out that it must
+	// be decoded again rune by rune, so they need the room to do so.
+	inPlaceGap = decodeBlock
+)
 
-	// if decode error; discard input remainder & bubble up error
+// readInPlace refills the decoder by reading directly into p, then decodes the
+// input there into the start of p. Only the 0-3 remainder bytes of an
+// incomplete rune are carried over in the internal buffer. It must only be
+// called when the internal buffer holds no more than those.
+func (d *Decoder) readInPlace(p []byte) (n int, err error) {
+	in := p[inPlaceGap:]
+	numCopy := copy(in, d.in)
+	var numRead int
+	numRead, d.err = readSome(d.r, in[numCopy:])
+	in = in[:numCopy+numRead]
+
+	n, nsrc, nskip, err := d.enc.decodeChunk(p, in)
 	if err != nil {
-		d.in, d.err = nil, err
+		d.in, d.err = nil, relocate(err, d.off, d.index)
+		err = d.err
+	} else {
+		d.in = d.arr[:copy(d.arr[:], in[nsrc:])]
 	}
+	d.off += int64(nsrc)
+	d.index += int64(n + nskip)
+	d.outOff += int64(n)
+	return n, err
+}
 
-	// only expose errors when buffer fully consumed
-	if len(d.in) < encodedByteSize {
-		return numDecodedBytes, d.err
+// WriteTo implements io.WriterTo. It decodes data until EOF or error, writing
+// the result to w, with buffers much larger than those used by Read. Any
+// input already buffered by a previous Read is decoded first. The return value
+// n is the number of bytes written to w. An error writing to w is sticky, just
+// like a decoding error.
+func (d *Decoder) WriteTo(w io.Writer) (n int64, err error) {
+	if d.copyBuf == nil {
+		d.copyBuf = make([]byte, copyBufferSize+copyBufferSize/encodedByteSize)
 	}
+	buf, out := d.copyBuf[:copyBufferSize], d.copyBuf[copyBufferSize:] // out is enough for all of buf
+	in := buf[:copy(buf, d.in)]
+	d.in = nil
+
+	for {
+		if len(in) > 0 {
+			numDecoded, nsrc, nskip, derr := d.enc.decodeChunk(out, in)
+			if derr != nil {
+				derr = relocate(derr, d.off, d.index)
+			}
+			in = in[nsrc:]
+			d.off += int64(nsrc)
+			d.index += int64(numDecoded + nskip)
 
-	return numDecodedBytes, nil
+			if numDecoded > 0 {
+				written, werr := w.Write(out[:numDecoded])
+				n += int64(written)
+				d.outOff += int64(written)
+				if werr == nil && written < numDecoded {
+					werr = io.ErrShortWrite
+				}
+				if werr != nil {
+					d.err = werr
+					return n, werr
+				}
+			}
+			if derr != nil {
+				d.err = derr
+				return n, derr
+			}
+		}
+
+		if d.err != nil {
+			switch {
+			case d.err == io.EOF && len(in) > 0:
+				d.err = d.enc.trailingError(in, d.off, d.index)
+			case d.err == io.EOF:
+				return n, nil
+			}
+			return n, d.err
+		}
+
+		// Refill, carrying over the 0-3 remainder bytes as Read does.
+		numCopy := copy(buf, in)
+		var numRead int
+		numRead, d.err = readSome(d.r, buf[numCopy:])
+		in = buf[:numCopy+numRead]
+	}
 }
 
-// NewlineFilteringReader wraps an io.Reader and strips CRLF from the stream.
-//
-// This implementation is taken verbatim from the base64 module of the Go
-// standard library (where it is not exported, hence the copypasta).
+// relocate adjusts the position of a CorruptInputError returned by decodeChunk
+// to be relative to the start of a stream rather than an internal buffer, the
+// start of which is at the given byte offset and rune index of the stream.
+func relocate(err error, offset, index int64) error {
+	if e, ok := err.(CorruptInputError); ok {
+		e.Offset += offset
+		e.Index += index
+		return e
+	}
+	return err
+}
+
+/* DECODING WRITER */
+
+// NewDecodingWriter returns a writer which decodes the base100 data written to
+// it, and writes the decoded result to w. It is the push based counterpart to
+// NewDecoder, for when encoded data arrives in chunks to be handed off, rather
+// than from an io.Reader.
 //
-// Currently this is not utilized the decoder by default, since it hits
-// performance very hard. Leaving here for now as a hint for the future.
-
-/*
-type NewlineFilteringReader struct {
-	wrapped io.Reader
-}
-
-func (r *NewlineFilteringReader) Read(p []byte) (int, error) {
-	n, err := r.wrapped.Read(p)
-	for n > 0 {
-		offset := 0
-		for i, b := range p[:n] {
-			if b != '\r' && b != '\n' {
-				if i != offset {
-					p[offset] = b
-				}
-				offset++
+// Data may be written in arbitrary chunks, including ones which split a rune.
+// When finished writing, the caller must Close the returned writer to check for
+// a dangling incomplete rune at the end of the input.
+func NewDecodingWriter(w io.Writer) io.WriteCloser {
+	return StdEncoding.NewDecodingWriter(w)
+}
+
+// NewDecodingWriter returns a writer which decodes data written to it using
+// enc, and writes the decoded result to w. See the package level
+// NewDecodingWriter.
+func (enc *Encoding) NewDecodingWriter(w io.Writer) io.WriteCloser {
+	return &decodingWriter{enc: enc, w: w}
+}
+
+type decodingWriter struct {
+	enc   *Encoding
+	w     io.Writer
+	err   error
+	off   int64                              // input offset of in[0], for error reporting
+	index int64                              // input rune index of in[0], for error reporting
+	in    []byte                             // input buffer (encoded form)
+	arr   [bufferSize]byte                   // backing array for in
+	out   [bufferSize / encodedByteSize]byte // output buffer, enough for all of arr
+}
+
+func (dw *decodingWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 && dw.err == nil {
+		// Stage input after any incomplete rune left over from last time, so
+		// that it can be completed.
+		carried := len(dw.in)
+		numCopy := copy(dw.arr[carried:], p)
+		dw.in = dw.arr[:carried+numCopy]
+		p = p[numCopy:]
+		n += numCopy
+
+		numDecoded, nsrc, nskip, err := dw.enc.decodeChunk(dw.out[:], dw.in)
+		if numDecoded > 0 {
+			written, werr := dw.w.Write(dw.out[:numDecoded])
+			if werr == nil && written < numDecoded {
+				werr = io.ErrShortWrite
+			}
+			if werr != nil {
+				// Only count the input staged up to the end of the last rune
+				// whose decoded byte went out, found by decoding it again.
+				_, nwritten, _, _ := dw.enc.decodeChunk(dw.out[:written], dw.in)
+				n -= numCopy - max(nwritten-carried, 0)
+				dw.err = werr
 			}
 		}
-		if offset > 0 {
-			return offset, err
+		if err != nil && dw.err == nil {
+			dw.err = relocate(err, dw.off, dw.index)
+			n = max(n-(len(dw.in)-nsrc), 0) // don't count the bytes from the error on
 		}
-		// Previous buffer entirely whitespace, read again
-		n, err = r.wrapped.Read(p)
+
+		dw.off += int64(nsrc)
+		dw.index += int64(numDecoded + nskip)
+		dw.in = dw.arr[:copy(dw.arr[:], dw.in[nsrc:])] // move remainder bytes [0-3] to the front
 	}
-	return n, err
+	return n, dw.err
+}
+
+// Close reports an error if the input written so far ends with an incomplete
+// rune. It does not close the underlying writer.
+func (dw *decodingWriter) Close() error {
+	if dw.err == nil && len(dw.in) > 0 {
+		dw.err = dw.enc.trailingError(dw.in, dw.off, dw.index)
+	}
+	return dw.err
 }
-*/
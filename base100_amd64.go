@@ -0,0 +1,45 @@
+//go:build amd64 && !purego
+
+package base100
+
+// useAVX2 reports whether to use the AVX2 assembly kernels, which can be
+// turned off by building with the purego tag.
+var useAVX2 = hasAVX2()
+
+// encodeAVX2 encodes as many of the runes for src into dst as it can in blocks
+// of 16, returning the number of bytes of src encoded.
+//
+//go:noescape
+func encodeAVX2(dst, src []byte) int
+
+// decodeAVX2 decodes runes from src into dst in blocks of 32, for as long as
+// each block passes the check of decodeRunStrict if strict is set, or else
+// decodeRun. It returns the number of runes decoded, and does not write to dst
+// past them.
+//
+//go:noescape
+func decodeAVX2(dst, src []byte, strict bool) int
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+func xgetbv() (eax, edx uint32)
+
+// hasAVX2 reports whether the CPU supports AVX2, and the OS saves the state of
+// the YMM registers. It is written out here rather than taking a dependency on
+// golang.org/x/sys/cpu for the sake of a single feature bit.
+func hasAVX2() bool {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return false
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	const osxsave, avx = 1 << 27, 1 << 28
+	if ecx1&osxsave == 0 || ecx1&avx == 0 {
+		return false
+	}
+	if xcr0, _ := xgetbv(); xcr0&0b110 != 0b110 { // XMM and YMM state
+		return false
+	}
+	_, ebx7, _, _ := cpuid(7, 0)
+	const avx2 = 1 << 5
+	return ebx7&avx2 != 0
+}
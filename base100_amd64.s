@@ -0,0 +1,183 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
+
+// Word constants for encodeAVX2.
+DATA encodeConsts<>+0(SB)/2, $55     // offset to add to each byte
+DATA encodeConsts<>+2(SB)/2, $0x003f // low six bits
+DATA encodeConsts<>+4(SB)/2, $0x808f // 143 into byte 3, 128 into byte 4
+DATA encodeConsts<>+6(SB)/2, $0x9ff0 // fixed first two bytes of each rune
+GLOBL encodeConsts<>(SB), RODATA|NOPTR, $8
+
+// func encodeAVX2(dst, src []byte) int
+//
+// Each iteration zero extends 16 bytes of input to 16 words, and computes the
+// last two bytes of each rune there, as in encodeTable. These are interleaved
+// with the fixed first two bytes, giving 16 runes, which are written out as 64
+// bytes of output.
+TEXT ·encodeAVX2(SB), NOSPLIT, $0-56
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), AX
+	MOVQ src_base+24(FP), SI
+	MOVQ src_len+32(FP), BX
+
+	// AX = min(len(dst)/4, len(src)), rounded down to a multiple of 16
+	SHRQ    $2, AX
+	CMPQ    BX, AX
+	CMOVQLT BX, AX
+	ANDQ    $-16, AX
+	MOVQ    AX, ret+48(FP)
+	JZ      encodeDone
+
+	VPBROADCASTW encodeConsts<>+0(SB), Y8
+	VPBROADCASTW encodeConsts<>+2(SB), Y9
+	VPBROADCASTW encodeConsts<>+4(SB), Y10
+	VPBROADCASTW encodeConsts<>+6(SB), Y11
+	XORQ         CX, CX
+
+encodeLoop:
+	VPMOVZXBW (SI)(CX*1), Y0
+	VPADDW    Y8, Y0, Y0     // w = b + 55
+	VPSRLW    $6, Y0, Y1     // w / 64
+	VPAND     Y9, Y0, Y2     // w % 64
+	VPSLLW    $8, Y2, Y2
+	VPADDW    Y2, Y1, Y1
+	VPADDW    Y10, Y1, Y1    // last two bytes of each rune
+	VPUNPCKLWD Y1, Y11, Y4    // runes 0-3 and 8-11
+	VPUNPCKHWD Y1, Y11, Y5    // runes 4-7 and 12-15
+	VPERM2I128 $0x20, Y5, Y4, Y6 // runes 0-7
+	VPERM2I128 $0x31, Y5, Y4, Y7 // runes 8-15
+	VMOVDQU    Y6, (DI)
+	VMOVDQU    Y7, 32(DI)
+	ADDQ       $64, DI
+	ADDQ       $16, CX
+	CMPQ       CX, AX
+	JB         encodeLoop
+
+	VZEROUPPER
+
+encodeDone:
+	RET
+
+// Dword constants for decodeAVX2. The check masks come in two sets, without
+// and with strict validation, each of: the bits of the input to check, what
+// they must be, and the bits of the decoded value which must be zero.
+DATA decodeConsts<>+0(SB)/4, $0x000000ff
+DATA decodeConsts<>+4(SB)/4, $0x000000f0
+DATA decodeConsts<>+8(SB)/4, $0x00000000
+DATA decodeConsts<>+12(SB)/4, $0xc000ffff
+DATA decodeConsts<>+16(SB)/4, $0x80009ff0
+DATA decodeConsts<>+20(SB)/4, $0xffffff00
+DATA decodeConsts<>+24(SB)/4, $0x000000ff // low byte
+DATA decodeConsts<>+28(SB)/4, $9335       // 143<<6 + 128 + 55
+GLOBL decodeConsts<>(SB), RODATA|NOPTR, $32
+
+// Dword permutation to restore the order of the runes after packing.
+DATA decodePerm<>+0(SB)/4, $0
+DATA decodePerm<>+4(SB)/4, $4
+DATA decodePerm<>+8(SB)/4, $1
+DATA decodePerm<>+12(SB)/4, $5
+DATA decodePerm<>+16(SB)/4, $2
+DATA decodePerm<>+20(SB)/4, $6
+DATA decodePerm<>+24(SB)/4, $3
+DATA decodePerm<>+28(SB)/4, $7
+GLOBL decodePerm<>(SB), RODATA|NOPTR, $32
+
+// DECODE8 decodes the eight runes in register in, each in its own dword, as in
+// decodeRune, leaving the decoded bytes in the low byte of each dword of in.
+// Anything wrong with the runes is ORed into Y6.
+#define DECODE8(in) \
+	VPAND  Y8, in, Y4  \
+	VPXOR  Y9, Y4, Y4  \
+	VPOR   Y4, Y6, Y6  \
+	VPSRLD $16, in, Y4 \
+	VPAND  Y11, Y4, Y5 \
+	VPSLLD $6, Y5, Y5  \
+	VPSRLD $8, Y4, Y4  \
+	VPADDD Y4, Y5, Y5  \
+	VPSUBD Y12, Y5, Y5 \
+	VPAND  Y10, Y5, Y4 \
+	VPOR   Y4, Y6, Y6  \
+	VPAND  Y11, Y5, in
+
+// func decodeAVX2(dst, src []byte, strict bool) int
+//
+// Each iteration decodes a block of 32 runes from 128 bytes of input, eight to
+// a register, then packs the decoded bytes together. A block with anything
+// wrong in it is left for the caller, without having written to dst.
+TEXT ·decodeAVX2(SB), NOSPLIT, $0-64
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), AX
+	MOVQ src_base+24(FP), SI
+	MOVQ src_len+32(FP), BX
+
+	// AX = min(len(dst), len(src)/4), rounded down to a multiple of 32
+	SHRQ    $2, BX
+	CMPQ    BX, AX
+	CMOVQLT BX, AX
+	ANDQ    $-32, AX
+	XORQ    CX, CX
+	TESTQ   AX, AX
+	JZ      decodeDone
+
+	LEAQ    decodeConsts<>(SB), R8
+	MOVBLZX strict+48(FP), R9
+	IMULQ   $12, R9
+	ADDQ    R9, R8
+	VPBROADCASTD 0(R8), Y8
+	VPBROADCASTD 4(R8), Y9
+	VPBROADCASTD 8(R8), Y10
+	VPBROADCASTD decodeConsts<>+24(SB), Y11
+	VPBROADCASTD decodeConsts<>+28(SB), Y12
+	VMOVDQU      decodePerm<>(SB), Y13
+
+decodeLoop:
+	VMOVDQU 0(SI), Y0
+	VMOVDQU 32(SI), Y1
+	VMOVDQU 64(SI), Y2
+	VMOVDQU 96(SI), Y3
+	VPXOR   Y6, Y6, Y6
+	DECODE8(Y0)
+	DECODE8(Y1)
+	DECODE8(Y2)
+	DECODE8(Y3)
+	VPTEST  Y6, Y6
+	JNZ     decodeStop
+
+	VPACKUSDW Y1, Y0, Y0 // within each lane: runes of Y0, then Y1
+	VPACKUSDW Y3, Y2, Y2
+	VPACKUSWB Y2, Y0, Y0 // within each lane: runes of Y0, Y1, Y2, then Y3
+	VPERMD    Y0, Y13, Y0
+	VMOVDQU   Y0, (DI)
+
+	ADDQ $128, SI
+	ADDQ $32, DI
+	ADDQ $32, CX
+	CMPQ CX, AX
+	JB   decodeLoop
+
+decodeStop:
+	VZEROUPPER
+
+decodeDone:
+	MOVQ CX, ret+56(FP)
+	RET
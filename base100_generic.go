@@ -0,0 +1,14 @@
+//go:build !amd64 || purego
+
+package base100
+
+// useAVX2 is always false where there is no assembly, see base100_amd64.go.
+var useAVX2 = false
+
+func encodeAVX2(dst, src []byte) int {
+	panic("base100: unreachable")
+}
+
+func decodeAVX2(dst, src []byte, strict bool) int {
+	panic("base100: unreachable")
+}
@@ -3,9 +3,13 @@ package base100
 import (
 	"bytes"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 var samplecases = []struct {
@@ -42,6 +46,30 @@ func TestEncodeToString(t *testing.T) {
 	}
 }
 
+func TestAppendEncode(t *testing.T) {
+	for n, sc := range samplecases {
+		t.Run(fmt.Sprintf("sample%02d", n), func(t *testing.T) {
+			prefix := []byte("prefix:")
+			want := slices.Concat(prefix, sc.text)
+
+			if got := AppendEncode(slices.Clone(prefix), sc.data); !bytes.Equal(got, want) {
+				t.Errorf("AppendEncode() = %q, want %q", got, want)
+			}
+
+			// with enough capacity already, the buffer should be reused
+			buf := make([]byte, len(prefix), len(want))
+			copy(buf, prefix)
+			got := AppendEncode(buf, sc.data)
+			if !bytes.Equal(got, want) {
+				t.Errorf("AppendEncode() = %q, want %q", got, want)
+			}
+			if &got[0] != &buf[0] {
+				t.Errorf("AppendEncode() reallocated despite sufficient capacity")
+			}
+		})
+	}
+}
+
 func TestDecode(t *testing.T) {
 	// handle all "normal" sample cases
 	for n, sc := range samplecases {
@@ -49,102 +77,1616 @@ func TestDecode(t *testing.T) {
 			src := sc.text
 			dst := make([]byte, DecodedLen(len(src)))
 
-			n, err := Decode(dst, src)
-			var (
-				wantN   = len(sc.data)
-				wantErr = false
-				wantDst = sc.data
-			)
-			if n != wantN {
-				t.Errorf("n = %v, want %v", n, wantN)
+			n, err := Decode(dst, src)
+			var (
+				wantN   = len(sc.data)
+				wantErr = false
+				wantDst = sc.data
+			)
+			if n != wantN {
+				t.Errorf("n = %v, want %v", n, wantN)
+			}
+			if (err != nil) != wantErr {
+				t.Errorf("err = %v, wantError: %v", err, wantErr)
+			}
+			if !bytes.Equal(dst, wantDst) {
+				t.Errorf("dst = %v, want %v", dst, wantDst)
+			}
+		})
+	}
+
+	// expect an error when dst too small
+	t.Run("dst too small", func(t *testing.T) {
+		const deficitBytes = 1
+		src := samplecases[0].text
+		dst := make([]byte, DecodedLen(len(src))-deficitBytes) // too small
+		_, err := Decode(dst, src)
+		if !errors.Is(err, ErrShortDst) {
+			t.Fatalf("expected ErrShortDst, got %v", err)
+		}
+	})
+
+	// expect a truncated error, and everything up to it decoded, when src
+	// ends partway through a rune
+	t.Run("truncated", func(t *testing.T) {
+		src := slices.Concat(samplecases[0].text, []byte{0xf0, 0x9f})
+		dst := make([]byte, DecodedLen(len(src)))
+		n, err := Decode(dst, src)
+		if want := TruncatedInputError(2); err != want {
+			t.Errorf("err = %v, want %v", err, want)
+		}
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("errors.Is(%v, io.ErrUnexpectedEOF) = false, want true", err)
+		}
+		if want := len(samplecases[0].data); n != want {
+			t.Errorf("n = %v, want %v", n, want)
+		}
+	})
+}
+
+func TestAppendDecode(t *testing.T) {
+	for n, sc := range samplecases {
+		t.Run(fmt.Sprintf("sample%02d", n), func(t *testing.T) {
+			prefix := []byte("prefix:")
+			want := slices.Concat(prefix, sc.data)
+
+			got, err := AppendDecode(slices.Clone(prefix), sc.text)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("AppendDecode() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	// whitespace takes up room in src but not dst, so the result should be
+	// sized by what was actually decoded
+	t.Run("whitespace", func(t *testing.T) {
+		got, err := AppendDecode(nil, []byte("👫👟👜\r\n\r\n\r\n"))
+		if want := []byte("the"); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("AppendDecode() = %q, %v, want %q, nil", got, err, want)
+		}
+	})
+
+	// on error, what was decoded up to that point should still be appended
+	t.Run("invalid", func(t *testing.T) {
+		got, err := StdEncoding.Strict().AppendDecode([]byte("x"), []byte("👫👟👜hello"))
+		if want := []byte("xthe"); !bytes.Equal(got, want) {
+			t.Errorf("AppendDecode() = %q, want %q", got, want)
+		}
+		if want := (CorruptInputError{Offset: 12, Index: 3}); err != want {
+			t.Errorf("err = %v, want %v", err, want)
+		}
+	})
+}
+
+func TestDecodeString(t *testing.T) {
+	// handle all "normal" sample cases
+	for n, sc := range samplecases {
+		t.Run(fmt.Sprintf("sample%02d", n), func(t *testing.T) {
+			src := string(sc.text)
+			got, err := DecodeString(src)
+			if wantErr := false; (err != nil) != wantErr {
+				t.Errorf("err = %v, wantError: %v", err, wantErr)
+			}
+			if want := sc.data; !bytes.Equal(got, want) {
+				t.Errorf("result = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStringAllocs(t *testing.T) {
+	data := allBytes()
+	text := EncodeToString(data)
+	encodings := []struct {
+		name   string
+		enc    *Encoding
+		allocs float64 // by EncodeToString
+	}{
+		{"std", StdEncoding, 1},
+		{"strict", StdEncoding.Strict(), 1},
+		{"presentation", StdEncoding.EmojiPresentation(), 2}, // copied out of the worst case buffer
+	}
+	for _, tc := range encodings {
+		t.Run(tc.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, func() { tc.enc.EncodeToString(data) }); allocs != tc.allocs {
+				t.Errorf("EncodeToString() allocs = %v, want %v", allocs, tc.allocs)
+			}
+			allocs := testing.AllocsPerRun(100, func() {
+				if _, err := tc.enc.DecodeString(text); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if allocs != 1 {
+				t.Errorf("DecodeString() allocs = %v, want 1", allocs)
+			}
+		})
+	}
+}
+
+var invalidcases = []struct {
+	name string
+	text []byte
+}{
+	{"ascii", []byte("hello")},
+	{"latin1", []byte("héllo")},
+	{"other emoji", []byte("🙂")},
+	{"bad prefix", []byte("\xf0\x9e\x91\xab")},
+	{"below table", []byte("🏶")}, // U+1F3F6
+	{"above table", []byte("📷")}, // U+1F4F7
+	{"bad continuation", []byte("\xf0\x9f\x92\x0a")},
+	{"truncated", []byte("👫👟\xf0\x9f")},
+	{"valid then invalid", []byte("👫👟hello!")},
+}
+
+func TestDecodeStrict(t *testing.T) {
+	for n, sc := range samplecases {
+		t.Run(fmt.Sprintf("sample%02d", n), func(t *testing.T) {
+			dst := make([]byte, DecodedLen(len(sc.text)))
+			n, err := DecodeStrict(dst, sc.text)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if n != len(sc.data) {
+				t.Errorf("n = %v, want %v", n, len(sc.data))
+			}
+			if !bytes.Equal(dst, sc.data) {
+				t.Errorf("dst = %v, want %v", dst, sc.data)
+			}
+		})
+	}
+
+	// every possible byte value should round trip
+	t.Run("all bytes", func(t *testing.T) {
+		src := make([]byte, 256)
+		for i := range src {
+			src[i] = byte(i)
+		}
+		encoded := make([]byte, EncodedLen(len(src)))
+		Encode(encoded, src)
+		got, err := DecodeStringStrict(string(encoded))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(got, src) {
+			t.Errorf("got %v, want %v", got, src)
+		}
+	})
+
+	for _, tc := range invalidcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := make([]byte, DecodedLen(len(tc.text)))
+			if _, err := DecodeStrict(dst, tc.text); err == nil {
+				t.Errorf("DecodeStrict(%q): expected error, got nil", tc.text)
+			}
+			if _, err := DecodeStringStrict(string(tc.text)); err == nil {
+				t.Errorf("DecodeStringStrict(%q): expected error, got nil", tc.text)
+			}
+		})
+	}
+}
+
+func TestDecodeStrictErrors(t *testing.T) {
+	prefix := samplecases[0].text // 45 valid runes, 180 bytes
+	testcases := []struct {
+		name    string
+		text    []byte
+		wantN   int
+		wantErr error
+	}{
+		{"ascii", []byte("hello"), 0, CorruptInputError{Offset: 0, Index: 0}},
+		{"after valid", slices.Concat(prefix, []byte("héllo")), 45, CorruptInputError{Offset: 180, Index: 45}},
+		{"truncated", slices.Concat(prefix, []byte{0xf0, 0x9f, 0x91}), 45, TruncatedInputError(3)},
+		{"corrupt tail", slices.Concat(prefix, []byte("hi")), 45, CorruptInputError{Offset: 180, Index: 45}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := make([]byte, DecodedLen(len(tc.text)))
+			n, err := DecodeStrict(dst, tc.text)
+			if err != tc.wantErr {
+				t.Errorf("err = %v, want %v", err, tc.wantErr)
+			}
+			if n != tc.wantN {
+				t.Errorf("n = %v, want %v", n, tc.wantN)
+			}
+		})
+	}
+}
+
+// whitespacecases are the encoded form of "the quick", with whitespace
+// inserted in between runes in a variety of ways.
+var whitespacecases = []struct {
+	name string
+	text string
+}{
+	{"trailing LF", "👫👟👜🐗👨👬👠👚👢\n"},
+	{"trailing CRLF", "👫👟👜🐗👨👬👠👚👢\r\n"},
+	{"leading space", "  👫👟👜🐗👨👬👠👚👢"},
+	{"wrapped LF", "👫👟👜\n🐗👨👬\n👠👚👢\n"},
+	{"wrapped CRLF", "👫👟👜\r\n🐗👨👬\r\n👠👚👢\r\n"},
+	{"spaces and tabs", "👫 👟 👜\t🐗\t👨 \t 👬👠👚👢"},
+	{"long run", "👫👟👜🐗👨👬👠👚👢" + strings.Repeat(" ", 100) + "\n"},
+}
+
+func TestDecodeWhitespace(t *testing.T) {
+	want := []byte("the quick")
+	for _, tc := range whitespacecases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := make([]byte, DecodedLen(len(tc.text)))
+			n, err := Decode(dst, []byte(tc.text))
+			if err != nil {
+				t.Errorf("Decode: unexpected error: %v", err)
+			}
+			if got := dst[:n]; !bytes.Equal(got, want) {
+				t.Errorf("Decode = %q, want %q", got, want)
+			}
+
+			got, err := DecodeStringStrict(tc.text)
+			if err != nil {
+				t.Errorf("DecodeStringStrict: unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("DecodeStringStrict = %q, want %q", got, want)
+			}
+
+			if !Valid([]byte(tc.text)) {
+				t.Errorf("Valid = false, want true")
+			}
+
+			// one byte at a time, so that every possible split of the input
+			// across reads gets exercised
+			got, err = io.ReadAll(NewStrictDecoder(iotest.OneByteReader(strings.NewReader(tc.text))))
+			if err != nil {
+				t.Errorf("NewStrictDecoder: unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("NewStrictDecoder = %q, want %q", got, want)
+			}
+		})
+	}
+
+	// whitespace still counts towards the rune index of an error
+	t.Run("error index", func(t *testing.T) {
+		_, err := DecodeStringStrict("👫\r\n👟\r\nhello")
+		if want := (CorruptInputError{Offset: 12, Index: 6}); err != want {
+			t.Errorf("err = %v, want %v", err, want)
+		}
+	})
+}
+
+func TestValid(t *testing.T) {
+	for n, sc := range samplecases {
+		t.Run(fmt.Sprintf("sample%02d", n), func(t *testing.T) {
+			if !Valid(sc.text) {
+				t.Errorf("Valid(%q) = false, want true", sc.text)
+			}
+		})
+	}
+	t.Run("empty", func(t *testing.T) {
+		if !Valid(nil) {
+			t.Error("Valid(nil) = false, want true")
+		}
+	})
+	for _, tc := range invalidcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if Valid(tc.text) {
+				t.Errorf("Valid(%q) = true, want false", tc.text)
+			}
+		})
+	}
+}
+
+// allBytes returns every possible byte value, in order.
+func allBytes() []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestEncoding(t *testing.T) {
+	data := slices.Concat(bytes.Repeat(samplecases[0].data, 10), allBytes())
+	encodings := []struct {
+		name string
+		enc  *Encoding
+	}{
+		{"std", StdEncoding},
+		{"strict", StdEncoding.Strict()},
+		{"no whitespace", StdEncoding.WithWhitespace(false)},
+		{"wrap LF", StdEncoding.WithWrap(76, LF)},
+		{"wrap CRLF strict", StdEncoding.WithWrap(7, CRLF).Strict()},
+		{"wrap exact", StdEncoding.WithWrap(45, LF)},
+		{"wrap disabled", StdEncoding.WithWrap(76, LF).WithWrap(0, LF)},
+		{"skin tone safe", SkinToneSafeEncoding},
+		{"presentation", StdEncoding.EmojiPresentation()},
+		{"presentation strict", StdEncoding.EmojiPresentation().Strict()},
+		{"presentation wrap safe", StdEncoding.WithWrap(7, CRLF).SkinToneSafe().EmojiPresentation()},
+	}
+	for _, tc := range encodings {
+		t.Run(tc.name, func(t *testing.T) {
+			enc := tc.enc
+			encoded := make([]byte, enc.EncodedLen(len(data)))
+			encoded = encoded[:enc.Encode(encoded, data)]
+
+			// in-memory and streaming encoding must agree, regardless of how
+			// the input is split up
+			for _, chunkSize := range []int{1, 13, len(data)} {
+				var buf bytes.Buffer
+				w := enc.NewEncoder(&buf)
+				for p := data; len(p) > 0; p = p[min(len(p), chunkSize):] {
+					w.Write(p[:min(len(p), chunkSize)])
+				}
+				w.Close()
+				if !bytes.Equal(encoded, buf.Bytes()) {
+					t.Errorf("Encode = %q, but NewEncoder wrote %q", encoded, buf.Bytes())
+				}
+			}
+			if got := enc.EncodeToString(data); got != string(encoded) {
+				t.Errorf("EncodeToString = %q, want %q", got, encoded)
+			}
+
+			// and round trip through all the decoding methods
+			decoded := make([]byte, enc.DecodedLen(len(encoded)))
+			n, err := enc.Decode(decoded, encoded)
+			if err != nil || !bytes.Equal(decoded[:n], data) {
+				t.Errorf("Decode = %q, %v, want %q, nil", decoded[:n], err, data)
+			}
+			decoded, err = enc.DecodeString(string(encoded))
+			if err != nil || !bytes.Equal(decoded, data) {
+				t.Errorf("DecodeString = %q, %v, want %q, nil", decoded, err, data)
+			}
+			decoded, err = io.ReadAll(enc.NewDecoder(iotest.OneByteReader(bytes.NewReader(encoded))))
+			if err != nil || !bytes.Equal(decoded, data) {
+				t.Errorf("NewDecoder = %q, %v, want %q, nil", decoded, err, data)
+			}
+			if !enc.Valid(encoded) {
+				t.Errorf("Valid = false, want true")
+			}
+		})
+	}
+
+	// deriving a new encoding must not affect the original
+	if *StdEncoding != (Encoding{whitespace: true}) {
+		t.Errorf("StdEncoding modified: %+v", *StdEncoding)
+	}
+}
+
+func TestEncodingWithoutWhitespace(t *testing.T) {
+	const text = "👫👟👜\n"
+
+	strict := StdEncoding.WithWhitespace(false).Strict()
+	if _, err := strict.DecodeString(text); err != (CorruptInputError{Offset: 12, Index: 3}) {
+		t.Errorf("strict: err = %v, want CorruptInputError", err)
+	}
+	if strict.Valid([]byte(text)) {
+		t.Errorf("strict: Valid = true, want false")
+	}
+
+	// Without validation, the newline is simply the start of a (truncated)
+	// garbage rune, as far as the decoder is concerned.
+	lax := StdEncoding.WithWhitespace(false)
+	if _, err := lax.DecodeString(text); err != TruncatedInputError(1) {
+		t.Errorf("lax: err = %v, want TruncatedInputError(1)", err)
+	}
+}
+
+func TestSkinToneSafe(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+
+	enc := SkinToneSafeEncoding
+	encoded := enc.EncodeToString(all)
+	for _, r := range encoded {
+		if r >= 0x1f3fb && r <= 0x1f3ff {
+			t.Errorf("encoded output contains skin tone modifier %U", r)
+		}
+	}
+	if got, want := enc.EncodeToString([]byte{0x03, 0x04, 0x08, 0x09}), "🏺📷📻🐀"; got != want {
+		t.Errorf("EncodeToString() = %q, want %q", got, want)
+	}
+
+	// everything other than the remapped bytes should match the standard one
+	std := []rune(EncodeToString(all))
+	for i, r := range []rune(encoded) {
+		if (i < 0x04 || i > 0x08) && r != std[i] {
+			t.Errorf("byte %#02x: encoded as %U, want %U", i, r, std[i])
+		}
+	}
+
+	for _, strict := range []bool{false, true} {
+		enc := enc
+		if strict {
+			enc = enc.Strict()
+		}
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			got, err := enc.DecodeString(encoded)
+			if err != nil || !bytes.Equal(got, all) {
+				t.Errorf("DecodeString() = %v, %v, want %v, nil", got, err, all)
+			}
+
+			// stream decoding goes through the same code, but do it a byte at
+			// a time anyhow, to make sure the runes aren't split incorrectly
+			got, err = io.ReadAll(enc.NewDecoder(iotest.OneByteReader(strings.NewReader(encoded))))
+			if err != nil || !bytes.Equal(got, all) {
+				t.Errorf("NewDecoder() = %v, %v, want %v, nil", got, err, all)
+			}
+
+			// the standard forms are accepted too
+			got, err = enc.DecodeString("🏻🏼🏽🏾🏿")
+			if want := []byte{0x04, 0x05, 0x06, 0x07, 0x08}; err != nil || !bytes.Equal(got, want) {
+				t.Errorf("DecodeString() = %v, %v, want %v, nil", got, err, want)
+			}
+		})
+	}
+
+	// but anything else beyond the end of the standard alphabet is not
+	if _, err := enc.Strict().DecodeString("📼"); err == nil { // U+1F4FC
+		t.Errorf("expected error decoding U+1F4FC, got nil")
+	}
+	if _, err := StdEncoding.Strict().DecodeString(encoded); err == nil {
+		t.Errorf("expected error decoding with standard alphabet, got nil")
+	}
+}
+
+func TestEmojiPresentation(t *testing.T) {
+	enc := StdEncoding.EmojiPresentation()
+	if got, want := enc.EncodeToString([]byte{0x00, 0x01, 0x48, 0x49, 0x4a}), "🏷\ufe0f🏸🐿\ufe0f👀👁\ufe0f"; got != want {
+		t.Errorf("EncodeToString() = %+q, want %+q", got, want)
+	}
+
+	encoded := enc.EncodeToString(allBytes())
+	if got, want := len(encoded), EncodedLen(256)+3*len("\ufe0f"); got != want {
+		t.Errorf("encoded length = %d, want %d", got, want)
+	}
+	if got, max := len(encoded), enc.EncodedLen(256); got > max {
+		t.Errorf("encoded length %d exceeds EncodedLen %d", got, max)
+	}
+
+	// the decoder accepts both forms, so in particular output with variation
+	// selectors everywhere, or none at all
+	for _, text := range []string{
+		strings.ReplaceAll(encoded, "\ufe0f", ""),
+		strings.ReplaceAll(EncodeToString(allBytes()), "\U0001f409", "\U0001f409\ufe0f"),
+	} {
+		if got, err := enc.Strict().DecodeString(text); err != nil || !bytes.Equal(got, allBytes()) {
+			t.Errorf("DecodeString(%+q) = %v, %v, want all bytes", text, got, err)
+		}
+	}
+
+	// a truncated variation selector is reported as such
+	if _, err := enc.Strict().DecodeString("🏷\xef\xb8"); err != TruncatedInputError(2) {
+		t.Errorf("err = %v, want TruncatedInputError(2)", err)
+	}
+
+	// as does the standard encoding, along with whitespace
+	for _, dec := range []*Encoding{StdEncoding, StdEncoding.Strict()} {
+		if got, err := dec.DecodeString(encoded); err != nil || !bytes.Equal(got, allBytes()) {
+			t.Errorf("DecodeString() = %v, %v, want all bytes", got, err)
+		}
+		got, err := io.ReadAll(dec.NewDecoder(iotest.OneByteReader(strings.NewReader(encoded))))
+		if err != nil || !bytes.Equal(got, allBytes()) {
+			t.Errorf("NewDecoder() = %v, %v, want all bytes", got, err)
+		}
+	}
+
+	// but not one which is as literal as the Rust version
+	if _, err := StdEncoding.WithWhitespace(false).Strict().DecodeString(encoded); err != (CorruptInputError{Offset: 4, Index: 1}) {
+		t.Errorf("err = %v, want CorruptInputError", err)
+	}
+}
+
+func TestIgnoreFormatChars(t *testing.T) {
+	want := []byte("the quick")
+	texts := []string{
+		"👫\ufe0f👟\ufe0f👜\ufe0f🐗\ufe0f👨\ufe0f👬\ufe0f👠\ufe0f👚\ufe0f👢\ufe0f",
+		"👫\ufe0e👟👜\u200d🐗👨\ufe0e\ufe0f👬👠\u200d\u200d👚👢",
+		"\u200d👫👟👜\r\n\ufe0f🐗👨👬 \ufe0e 👠👚👢\n",
+	}
+
+	for _, enc := range []*Encoding{
+		StdEncoding.IgnoreFormatChars(),
+		StdEncoding.IgnoreFormatChars().Strict(),
+	} {
+		for i, text := range texts {
+			got, err := enc.DecodeString(text)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("DecodeString(texts[%d]) = %q, %v, want %q, nil", i, got, err, want)
+			}
+
+			// every possible split of the input across reads
+			got, err = io.ReadAll(enc.NewDecoder(iotest.OneByteReader(strings.NewReader(text))))
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("NewDecoder(texts[%d]) = %q, %v, want %q, nil", i, got, err, want)
+			}
+
+			if !enc.Valid([]byte(text)) {
+				t.Errorf("Valid(texts[%d]) = false, want true", i)
+			}
+		}
+	}
+
+	// without the option the format characters other than U+FE0F are a
+	// problem
+	if got, err := StdEncoding.Strict().DecodeString(texts[0]); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("DecodeString(texts[0]) = %q, %v, want %q, nil", got, err, want)
+	}
+	if _, err := StdEncoding.Strict().DecodeString(texts[1]); err != (CorruptInputError{Offset: 4, Index: 1}) {
+		t.Errorf("err = %v, want CorruptInputError", err)
+	}
+	if got, _ := DecodeString(texts[1]); bytes.Equal(got, want) {
+		t.Errorf("DecodeString(texts[1]) = %q, expected garbage", got)
+	}
+
+	// a format character cut short by the end of input is truncated, not
+	// corrupt, while one which is merely similar is corrupt
+	strict := StdEncoding.IgnoreFormatChars().Strict()
+	if _, err := strict.DecodeString("👫\xe2\x80"); err != TruncatedInputError(2) {
+		t.Errorf("err = %v, want TruncatedInputError(2)", err)
+	}
+	if _, err := strict.DecodeString("👫\u200c👟"); err != (CorruptInputError{Offset: 4, Index: 1}) { // ZWNJ
+		t.Errorf("err = %v, want CorruptInputError", err)
+	}
+}
+
+var (
+	encoderDecoderMults = []int{1, 8, 128, 192}
+)
+
+func TestEncoder(t *testing.T) {
+	for _, mult := range encoderDecoderMults {
+		for j, sc := range samplecases {
+			t.Run(fmt.Sprintf("mult%03d/sample%02d", mult, j), func(t *testing.T) {
+				decoded := bytes.Repeat(sc.data, mult)
+				encoded := bytes.Repeat(sc.text, mult)
+
+				var buf bytes.Buffer
+				enc := NewEncoder(&buf)
+				n, err := enc.Write(decoded)
+				if n != len(decoded) {
+					t.Errorf("n = %d, want %d", n, len(decoded))
+				}
+				if err != nil {
+					t.Errorf("got error: %v", err)
+				}
+				if got := buf.Bytes(); !bytes.Equal(encoded, got) {
+					t.Errorf("want %x got %x", encoded, got)
+				}
+			})
+		}
+	}
+}
+
+func TestEncoderReadFrom(t *testing.T) {
+	data := bytes.Repeat(allBytes(), 300)
+	for _, enc := range []*Encoding{StdEncoding, SkinToneSafeEncoding, StdEncoding.EmojiPresentation(), StdEncoding.WithWrap(76, CRLF)} {
+		var want bytes.Buffer
+		e := enc.NewEncoder(&want)
+		e.Write(slices.Concat(data[:1], data))
+		e.Close()
+
+		for _, r := range []io.Reader{bytes.NewReader(data), iotest.HalfReader(bytes.NewReader(data))} {
+			var got bytes.Buffer
+			e := enc.NewEncoder(&got)
+			e.Write(data[:1]) // leave a partial line to continue
+			n, err := e.ReadFrom(r)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if n != int64(len(data)) {
+				t.Errorf("n = %v, want %v", n, len(data))
+			}
+			if err := e.Close(); err != nil {
+				t.Fatalf("Close error: %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("ReadFrom output does not match Write")
+			}
+		}
+	}
+
+	t.Run("reader error", func(t *testing.T) {
+		wantErr := errors.New("upstream")
+		e := NewEncoder(io.Discard)
+		n, err := e.ReadFrom(io.MultiReader(strings.NewReader("hello"), iotest.ErrReader(wantErr)))
+		if err != wantErr {
+			t.Errorf("err = %v, want %v", err, wantErr)
+		}
+		if n != 5 {
+			t.Errorf("n = %v, want %v", n, 5)
+		}
+		if e.Err() != nil {
+			t.Errorf("reader error should not be sticky, Err = %v", e.Err())
+		}
+	})
+
+	t.Run("no progress", func(t *testing.T) {
+		r := &stallReader{r: strings.NewReader("hello")}
+		n, err := NewEncoder(io.Discard).ReadFrom(r)
+		if err != io.ErrNoProgress {
+			t.Errorf("err = %v, want %v", err, io.ErrNoProgress)
+		}
+		if n != 5 {
+			t.Errorf("n = %v, want %v", n, 5)
+		}
+		if r.reads > 1000 {
+			t.Errorf("ReadFrom made %d reads before giving up", r.reads)
+		}
+	})
+
+	t.Run("writer error", func(t *testing.T) {
+		wantErr := errors.New("downstream")
+		e := NewEncoder(errWriter{wantErr})
+		if _, err := e.ReadFrom(strings.NewReader("hello")); err != wantErr {
+			t.Errorf("err = %v, want %v", err, wantErr)
+		}
+	})
+
+	t.Run("short writes", func(t *testing.T) {
+		// Input read from r but not yet written is held for Flush, so that
+		// carrying on with ReadFrom loses nothing.
+		for _, enc := range []*Encoding{StdEncoding, StdEncoding.EmojiPresentation().WithWrap(7, CRLF)} {
+			var want bytes.Buffer
+			full := enc.NewEncoder(&want)
+			full.Write(data)
+			full.Close()
+
+			var got bytes.Buffer
+			e := enc.NewEncoder(&limitWriter{w: &got, limit: 100})
+			r := bytes.NewReader(data)
+			var total int64
+			for {
+				n, err := e.ReadFrom(r)
+				total += n
+				if err == nil {
+					break
+				}
+				if err != io.ErrShortWrite {
+					t.Fatalf("ReadFrom error: %v", err)
+				}
+				for err := e.Flush(); err != nil; err = e.Flush() {
+					if err != io.ErrShortWrite {
+						t.Fatalf("Flush error: %v", err)
+					}
+				}
+			}
+			for err := e.Close(); err != nil; err = e.Close() {
+				e.Flush()
+			}
+			if total != int64(len(data)) || r.Len() != 0 {
+				t.Errorf("n = %d in total with %d left unread, want %d", total, r.Len(), len(data))
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("ReadFrom output with short writes does not match Write, %d bytes vs %d", got.Len(), want.Len())
+			}
+		}
+	})
+}
+
+func TestWrappingEncoder(t *testing.T) {
+	// wrapped builds the expected output by hand, rune by rune
+	wrapped := func(data []byte, width int, eol string) string {
+		var sb strings.Builder
+		for i, b := range data {
+			sb.WriteString(EncodeToString([]byte{b}))
+			if (i+1)%width == 0 || i == len(data)-1 {
+				sb.WriteString(eol)
+			}
+		}
+		return sb.String()
+	}
+
+	data := bytes.Repeat(samplecases[0].data, 50)
+	testcases := []struct {
+		name  string
+		data  []byte
+		width int
+		eol   LineEnding
+		want  string
+	}{
+		{"LF", data, 76, LF, wrapped(data, 76, "\n")},
+		{"CRLF", data, 76, CRLF, wrapped(data, 76, "\r\n")},
+		{"narrow", data, 1, LF, wrapped(data, 1, "\n")},
+		{"wider than buffer", data, 1000, CRLF, wrapped(data, 1000, "\r\n")},
+		{"exact multiple", data[:90], 45, LF, wrapped(data[:90], 45, "\n")},
+		{"empty", nil, 76, LF, ""},
+		{"disabled", data, 0, LF, EncodeToString(data)},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// write in a variety of chunk sizes, to exercise lines spanning
+			// multiple calls to Write
+			for _, chunkSize := range []int{1, 7, 45, 4096} {
+				var buf bytes.Buffer
+				enc := NewWrappingEncoder(&buf, tc.width, tc.eol)
+				for p := tc.data; len(p) > 0; {
+					k := min(len(p), chunkSize)
+					n, err := enc.Write(p[:k])
+					if n != k || err != nil {
+						t.Fatalf("Write = %d, %v, want %d, nil", n, err, k)
+					}
+					p = p[k:]
+				}
+				if err := enc.Close(); err != nil {
+					t.Fatalf("Close: unexpected error: %v", err)
+				}
+				if got := buf.String(); got != tc.want {
+					t.Errorf("chunk size %d: got %q, want %q", chunkSize, got, tc.want)
+				}
+
+				// and the decoder should be able to read it right back
+				decoded, err := io.ReadAll(NewStrictDecoder(&buf))
+				if err != nil {
+					t.Fatalf("decoding: unexpected error: %v", err)
+				}
+				if !bytes.Equal(decoded, tc.data) {
+					t.Errorf("chunk size %d: round trip mismatch", chunkSize)
+				}
+			}
+		})
+	}
+}
+
+func TestEncoderReset(t *testing.T) {
+	for _, enc := range []*Encoding{StdEncoding, StdEncoding.WithWrap(3, CRLF)} {
+		var first, second bytes.Buffer
+		e := enc.NewEncoder(&first)
+		e.Write([]byte("hello"))
+		e.Close()
+		if got, want := e.InputOffset(), int64(5); got != want {
+			t.Errorf("InputOffset = %v, want %v", got, want)
+		}
+		if got, want := e.OutputOffset(), int64(first.Len()); got != want {
+			t.Errorf("OutputOffset = %v, want %v", got, want)
+		}
+
+		// A partial line must not leak across a Reset.
+		want := first.String()
+		e.Write([]byte("x"))
+		e.Reset(&second)
+		e.Write([]byte("hello"))
+		e.Close()
+		if second.String() != want {
+			t.Errorf("after Reset got %q, want %q", second.String(), want)
+		}
+		if got, want := e.InputOffset(), int64(5); got != want {
+			t.Errorf("after Reset InputOffset = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestEncoderErr(t *testing.T) {
+	wantErr := errors.New("downstream")
+	e := NewEncoder(errWriter{wantErr})
+	if err := e.Err(); err != nil {
+		t.Fatalf("Err = %v before writing", err)
+	}
+	if _, err := e.Write([]byte("hello")); err != wantErr {
+		t.Errorf("Write err = %v, want %v", err, wantErr)
+	}
+	if err := e.Err(); err != wantErr {
+		t.Errorf("Err = %v, want %v", err, wantErr)
+	}
+	if err := e.Close(); err != wantErr {
+		t.Errorf("Close = %v, want %v", err, wantErr)
+	}
+	e.Reset(io.Discard)
+	if err := e.Err(); err != nil {
+		t.Errorf("Err = %v after Reset", err)
+	}
+}
+
+func TestEncoderShortWrite(t *testing.T) {
+	data := slices.Concat(bytes.Repeat(allBytes(), 3), []byte("hello"))
+	encodings := []struct {
+		name string
+		enc  *Encoding
+	}{
+		{"std", StdEncoding},
+		{"presentation", StdEncoding.EmojiPresentation()},
+		{"wrapped", StdEncoding.WithWrap(5, CRLF)},
+		{"presentation wrapped", StdEncoding.EmojiPresentation().WithWrap(3, CRLF)},
+	}
+	for _, e := range encodings {
+		var want bytes.Buffer
+		full := e.enc.NewEncoder(&want)
+		full.Write(data)
+		full.Close()
+
+		// Every write comes up short, cutting runes and line endings in every
+		// possible place, yet retrying through Flush must lose nothing.
+		for _, limit := range []int{1, 3, 5, 6, 7, 1000} {
+			t.Run(fmt.Sprintf("%s/limit%d", e.name, limit), func(t *testing.T) {
+				var got bytes.Buffer
+				enc := e.enc.NewEncoder(&limitWriter{w: &got, limit: limit})
+				flush := func() {
+					for err := enc.Flush(); err != nil; err = enc.Flush() {
+						if err != io.ErrShortWrite {
+							t.Fatalf("Flush error: %v", err)
+						}
+					}
+				}
+
+				for p := data; len(p) > 0; {
+					n, err := enc.Write(p)
+					if n < len(p) && err == nil {
+						t.Fatalf("n = %d < %d with nil error", n, len(p))
+					}
+					if err != nil && err != io.ErrShortWrite {
+						t.Fatalf("err = %v, want %v", err, io.ErrShortWrite)
+					}
+					flush()
+					p = p[n:]
+				}
+				for err := enc.Close(); err != nil; err = enc.Close() {
+					flush()
+				}
+
+				if !bytes.Equal(got.Bytes(), want.Bytes()) {
+					t.Errorf("want %q got %q", want.Bytes(), got.Bytes())
+				}
+				if got, want := enc.InputOffset(), int64(len(data)); got != want {
+					t.Errorf("InputOffset = %v, want %v", got, want)
+				}
+				if got, want := enc.OutputOffset(), int64(want.Len()); got != want {
+					t.Errorf("OutputOffset = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+
+	t.Run("sticky", func(t *testing.T) {
+		wantErr := errors.New("downstream")
+		var got bytes.Buffer
+		w := &limitWriter{w: &got, limit: 6, err: wantErr}
+		enc := NewEncoder(w)
+
+		// "he" went out in part, the rest of its 'e' is held back.
+		n, err := enc.Write([]byte("hello"))
+		if n != 2 || err != wantErr {
+			t.Fatalf("Write = %d, %v, want %d, %v", n, err, 2, wantErr)
+		}
+		if n, err := enc.Write([]byte("llo")); n != 0 || err != wantErr {
+			t.Errorf("Write after error = %d, %v, want %d, %v", n, err, 0, wantErr)
+		}
+		if err := enc.Err(); err != wantErr {
+			t.Errorf("Err = %v, want %v", err, wantErr)
+		}
+
+		w.limit, w.err = 1000, nil // recovered
+		if err := enc.Flush(); err != nil {
+			t.Fatalf("Flush error: %v", err)
+		}
+		if _, err := enc.Write([]byte("llo")); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		if got, want := got.String(), EncodeToString([]byte("hello")); got != want {
+			t.Errorf("want %q got %q", want, got)
+		}
+	})
+
+	t.Run("short count", func(t *testing.T) {
+		var got bytes.Buffer
+		enc := NewEncoder(shortWriter{&got})
+		if n, err := enc.Write([]byte("hello")); n != 5 || err != io.ErrShortWrite {
+			t.Errorf("Write = %d, %v, want %d, %v", n, err, 5, io.ErrShortWrite)
+		}
+		if err := enc.Close(); err != io.ErrShortWrite {
+			t.Errorf("Close = %v, want %v", err, io.ErrShortWrite)
+		}
+		if got, want := got.String(), EncodeToString([]byte("hello")); got != want[:len(want)-1] {
+			t.Errorf("want %q got %q", want[:len(want)-1], got)
+		}
+	})
+
+	t.Run("iotest.TruncateWriter", func(t *testing.T) {
+		// TruncateWriter reports every write as complete, even once it has
+		// started dropping data, so there is no short count for the encoder
+		// to go on: what it can't see, it can't report.
+		var got bytes.Buffer
+		enc := NewEncoder(iotest.TruncateWriter(&got, 6))
+		if n, err := enc.Write([]byte("hello")); n != 5 || err != nil {
+			t.Errorf("Write = %d, %v, want %d, nil", n, err, 5)
+		}
+		if err := enc.Close(); err != nil {
+			t.Errorf("Close = %v, want nil", err)
+		}
+		if got, want := got.String(), EncodeToString([]byte("hello"))[:6]; got != want {
+			t.Errorf("want %q got %q", want, got)
+		}
+		if got, want := enc.OutputOffset(), int64(EncodedLen(5)); got != want {
+			t.Errorf("OutputOffset = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestEncodingReader(t *testing.T) {
+	data := slices.Concat(bytes.Repeat(samplecases[0].data, 50), allBytes())
+	encodings := []struct {
+		name string
+		enc  *Encoding
+	}{
+		{"std", StdEncoding},
+		{"wrap LF", StdEncoding.WithWrap(76, LF)},
+		{"wrap CRLF", StdEncoding.WithWrap(1, CRLF)},
+		{"wrap exact", StdEncoding.WithWrap(len(data)/2, LF)},
+		{"skin tone safe", SkinToneSafeEncoding},
+		{"presentation", StdEncoding.EmojiPresentation().WithWrap(10, LF)},
+	}
+	for _, tc := range encodings {
+		t.Run(tc.name, func(t *testing.T) {
+			want := tc.enc.EncodeToString(data)
+
+			// iotest.TestReader checks the io.Reader contract in all sorts
+			// of ways, including reading everything back
+			r := tc.enc.NewEncodingReader(bytes.NewReader(data))
+			if err := iotest.TestReader(r, []byte(want)); err != nil {
+				t.Error(err)
+			}
+
+			// the size of the underlying data is irrelevant to the output,
+			// as are the sizes of the reads
+			r = tc.enc.NewEncodingReader(iotest.HalfReader(iotest.OneByteReader(bytes.NewReader(data))))
+			got, err := io.ReadAll(r)
+			if err != nil || string(got) != want {
+				t.Errorf("ReadAll() = %q, %v, want %q, nil", got, err, want)
+			}
+
+			// empty input is empty output, even when wrapping
+			got, err = io.ReadAll(tc.enc.NewEncodingReader(bytes.NewReader(nil)))
+			if err != nil || len(got) != 0 {
+				t.Errorf("ReadAll() = %q, %v, want empty", got, err)
+			}
+		})
+	}
+
+	// errors from the underlying reader come through only after the data
+	// read before them
+	t.Run("error", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		src := io.MultiReader(bytes.NewReader([]byte("the")), iotest.ErrReader(errBoom))
+		got, err := io.ReadAll(NewEncodingReader(src))
+		if want := "👫👟👜"; err != errBoom || string(got) != want {
+			t.Errorf("ReadAll() = %q, %v, want %q, %v", got, err, want, errBoom)
+		}
+	})
+}
+
+func TestEncodingReaderNoProgress(t *testing.T) {
+	r := &stallReader{r: strings.NewReader("hi")}
+	er := NewEncodingReader(r)
+	if n, err := er.Read(nil); n != 0 || err != nil || r.reads != 0 {
+		t.Errorf("Read(nil) = %d, %v after %d reads, want 0, nil without reading", n, err, r.reads)
+	}
+
+	got, err := io.ReadAll(er)
+	if err != io.ErrNoProgress {
+		t.Errorf("ReadAll() error = %v, want %v", err, io.ErrNoProgress)
+	}
+	if string(got) != "👟👠" {
+		t.Errorf("ReadAll() = %q before giving up, want %q", got, "👟👠")
+	}
+	if r.reads > 1000 {
+		t.Errorf("ReadAll() made %d reads before giving up", r.reads)
+	}
+}
+
+func TestEncodingReaderLen(t *testing.T) {
+	data := bytes.Repeat(samplecases[0].data, 50)
+	for _, enc := range []*Encoding{
+		StdEncoding,
+		StdEncoding.WithWrap(76, LF),
+		StdEncoding.WithWrap(45, CRLF),
+		StdEncoding.WithWrap(1000, CRLF),
+	} {
+		r := enc.NewEncodingReader(bytes.NewReader(data))
+		remaining := enc.EncodedLen(len(data))
+		for {
+			if got := r.Len(); got != remaining {
+				t.Fatalf("Len() = %d, want %d", got, remaining)
+			}
+			n, err := r.Read(make([]byte, 333))
+			remaining -= n
+			if err == io.EOF {
+				break
+			}
+		}
+		if remaining != 0 {
+			t.Errorf("%d bytes unaccounted for", remaining)
+		}
+	}
+
+	// unknown when the underlying reader doesn't know, or the output length
+	// depends on the content
+	if got := NewEncodingReader(iotest.OneByteReader(bytes.NewReader(data))).Len(); got != -1 {
+		t.Errorf("Len() = %d, want -1", got)
+	}
+	if got := StdEncoding.EmojiPresentation().NewEncodingReader(bytes.NewReader(data)).Len(); got != -1 {
+		t.Errorf("Len() = %d, want -1", got)
+	}
+}
+
+// decoderCopyModes are the ways io.Copy can drive a Decoder, either through
+// its Read method, or through WriteTo when it is not hidden.
+var decoderCopyModes = []struct {
+	name string
+	wrap func(*Decoder) io.Reader
+}{
+	{"Read", func(d *Decoder) io.Reader { return struct{ io.Reader }{d} }},
+	{"WriteTo", func(d *Decoder) io.Reader { return d }},
+}
+
+func TestDecoder(t *testing.T) {
+	for _, mode := range decoderCopyModes {
+		for _, mult := range encoderDecoderMults {
+			for j, sc := range samplecases {
+				t.Run(fmt.Sprintf("%s/mult%03d/sample%02d", mode.name, mult, j), func(t *testing.T) {
+					decoded := bytes.Repeat(sc.data, mult)
+					encoded := bytes.Repeat(sc.text, mult)
+
+					var buf bytes.Buffer
+					dec := NewDecoder(bytes.NewReader(encoded))
+					n, err := io.Copy(&buf, mode.wrap(dec))
+					if n != int64(len(decoded)) {
+						t.Errorf("n = %d, want %d", n, len(decoded))
+					}
+					if err != nil {
+						t.Errorf("got error: %v", err)
+					}
+					if !bytes.Equal(decoded, buf.Bytes()) {
+						t.Errorf("want %q got %q", decoded, buf.Bytes())
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	// Enough valid runes that the error lands well past the first fill of the
+	// decoder's internal buffer, so offsets must be tracked across reads.
+	const numRunes = 1000
+	valid := bytes.Repeat([]byte("👫"), numRunes)
+
+	testcases := []struct {
+		name    string
+		strict  bool
+		text    []byte
+		wantErr error
+	}{
+		{"corrupt", true, slices.Concat(valid, []byte("hello")), CorruptInputError{Offset: 4 * numRunes, Index: numRunes}},
+		{"truncated", true, slices.Concat(valid, []byte{0xf0}), TruncatedInputError(1)},
+		{"corrupt tail", true, slices.Concat(valid, []byte("h")), CorruptInputError{Offset: 4 * numRunes, Index: numRunes}},
+		{"truncated nonstrict", false, slices.Concat(valid, []byte("hi")), TruncatedInputError(2)},
+	}
+	for _, mode := range decoderCopyModes {
+		for _, tc := range testcases {
+			t.Run(mode.name+"/"+tc.name, func(t *testing.T) {
+				dec := NewDecoder(bytes.NewReader(tc.text))
+				if tc.strict {
+					dec = NewStrictDecoder(bytes.NewReader(tc.text))
+				}
+				n, err := io.Copy(io.Discard, mode.wrap(dec))
+				if err != tc.wantErr {
+					t.Errorf("err = %v, want %v", err, tc.wantErr)
+				}
+				if n != numRunes {
+					t.Errorf("n = %v, want %v", n, numRunes)
+				}
+				if got := dec.InputOffset(); tc.strict && got != 4*numRunes {
+					t.Errorf("InputOffset = %v, want %v", got, 4*numRunes)
+				}
+			})
+		}
+	}
+}
+
+func TestDecoderLargeRead(t *testing.T) {
+	data := bytes.Repeat(allBytes(), 64)
+	encodings := []struct {
+		name string
+		enc  *Encoding
+	}{
+		{"std", StdEncoding},
+		{"strict", StdEncoding.Strict()},
+		{"skin tone safe", SkinToneSafeEncoding},
+		{"presentation", StdEncoding.EmojiPresentation().Strict()},
+		{"wrapped", StdEncoding.WithWrap(3, CRLF).Strict()},
+	}
+	sources := []struct {
+		name string
+		wrap func(io.Reader) io.Reader
+	}{
+		{"plain", func(r io.Reader) io.Reader { return r }},
+		{"half", iotest.HalfReader},
+		{"one byte", iotest.OneByteReader},
+		{"data err", iotest.DataErrReader},
+	}
+	for _, e := range encodings {
+		text := make([]byte, e.enc.EncodedLen(len(data)))
+		text = text[:e.enc.Encode(text, data)]
+		for _, src := range sources {
+			for _, size := range []int{largeReadSize, 1 << 20} {
+				t.Run(fmt.Sprintf("%s/%s/%d", e.name, src.name, size), func(t *testing.T) {
+					dec := e.enc.NewDecoder(src.wrap(bytes.NewReader(text)))
+					var got []byte
+					p := make([]byte, size)
+					for {
+						n, err := dec.Read(p)
+						got = append(got, p[:n]...)
+						if err == io.EOF {
+							break
+						}
+						if err != nil {
+							t.Fatalf("got error: %v", err)
+						}
+					}
+					if !bytes.Equal(got, data) {
+						t.Errorf("round trip mismatch")
+					}
+					if dec.InputOffset() != int64(len(text)) {
+						t.Errorf("InputOffset = %v, want %v", dec.InputOffset(), len(text))
+					}
+				})
+			}
+		}
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		const numRunes = 5000
+		valid := bytes.Repeat([]byte("👫"), numRunes)
+		testcases := []struct {
+			text    []byte
+			wantErr error
+		}{
+			{slices.Concat(valid, []byte("hello")), CorruptInputError{Offset: 4 * numRunes, Index: numRunes}},
+			{slices.Concat(valid, []byte{0xf0, 0x9f}), TruncatedInputError(2)},
+		}
+		for _, tc := range testcases {
+			dec := NewStrictDecoder(bytes.NewReader(tc.text))
+			p := make([]byte, largeReadSize)
+			var n int
+			var err error
+			for err == nil {
+				var m int
+				m, err = dec.Read(p)
+				n += m
+			}
+			if err != tc.wantErr {
+				t.Errorf("err = %v, want %v", err, tc.wantErr)
+			}
+			if n != numRunes {
+				t.Errorf("n = %v, want %v", n, numRunes)
+			}
+		}
+	})
+}
+
+func TestDecoderWriteTo(t *testing.T) {
+	text := strings.Repeat(whitespacecases[3].text, 1000)
+	want := strings.Repeat("the quick", 1000)
+
+	t.Run("after Read", func(t *testing.T) {
+		// Input buffered by a Read must not be lost by WriteTo.
+		dec := NewDecoder(iotest.HalfReader(strings.NewReader(text)))
+		p := make([]byte, 3)
+		n, err := io.ReadFull(dec, p)
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		var buf bytes.Buffer
+		buf.Write(p[:n])
+		if _, err := dec.WriteTo(&buf); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if buf.String() != want {
+			t.Errorf("round trip mismatch")
+		}
+		if dec.OutputOffset() != int64(len(want)) {
+			t.Errorf("OutputOffset = %v, want %v", dec.OutputOffset(), len(want))
+		}
+	})
+
+	t.Run("one byte reader", func(t *testing.T) {
+		var buf bytes.Buffer
+		dec := NewDecoder(iotest.OneByteReader(strings.NewReader(text)))
+		if _, err := dec.WriteTo(&buf); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if buf.String() != want {
+			t.Errorf("round trip mismatch")
+		}
+	})
+
+	t.Run("writer error", func(t *testing.T) {
+		wantErr := errors.New("downstream")
+		dec := NewDecoder(strings.NewReader(text))
+		if _, err := dec.WriteTo(errWriter{wantErr}); err != wantErr {
+			t.Errorf("err = %v, want %v", err, wantErr)
+		}
+		if _, err := dec.Read(make([]byte, 1)); err != wantErr {
+			t.Errorf("Read after failed WriteTo: err = %v, want %v", err, wantErr)
+		}
+	})
+
+	t.Run("short write", func(t *testing.T) {
+		var buf bytes.Buffer
+		dec := NewDecoder(strings.NewReader(text))
+		n, err := dec.WriteTo(shortWriter{&buf})
+		if err != io.ErrShortWrite {
+			t.Errorf("err = %v, want %v", err, io.ErrShortWrite)
+		}
+		if n != int64(buf.Len()) {
+			t.Errorf("n = %v, want %v", n, buf.Len())
+		}
+	})
+}
+
+func TestStrictDecoder(t *testing.T) {
+	for _, mult := range encoderDecoderMults {
+		for j, sc := range samplecases {
+			t.Run(fmt.Sprintf("mult%03d/sample%02d", mult, j), func(t *testing.T) {
+				decoded := bytes.Repeat(sc.data, mult)
+				encoded := bytes.Repeat(sc.text, mult)
+
+				var buf bytes.Buffer
+				dec := NewStrictDecoder(bytes.NewReader(encoded))
+				if _, err := io.Copy(&buf, dec); err != nil {
+					t.Errorf("got error: %v", err)
+				}
+				if !bytes.Equal(decoded, buf.Bytes()) {
+					t.Errorf("want %q got %q", decoded, buf.Bytes())
+				}
+			})
+		}
+	}
+
+	for _, tc := range invalidcases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := slices.Concat(bytes.Repeat(samplecases[0].text, 32), tc.text)
+			dec := NewStrictDecoder(bytes.NewReader(encoded))
+			if _, err := io.Copy(io.Discard, dec); err == nil {
+				t.Errorf("expected error, got nil")
 			}
-			if (err != nil) != wantErr {
-				t.Errorf("err = %v, wantError: %v", err, wantErr)
+		})
+	}
+}
+
+func TestDecoderReset(t *testing.T) {
+	text := []byte("👟👜👣👣👦\n")
+	d := NewStrictDecoder(bytes.NewReader([]byte("👟👜garbage")))
+	if _, err := io.ReadAll(d); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	d.Reset(bytes.NewReader(text))
+	if err := d.Err(); err != nil {
+		t.Fatalf("Err = %v after Reset", err)
+	}
+	got, err := io.ReadAll(d)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("want %q got %q", "hello", got)
+	}
+	if d.InputOffset() != int64(len(text)) {
+		t.Errorf("InputOffset = %v, want %v", d.InputOffset(), len(text))
+	}
+	if d.OutputOffset() != 5 {
+		t.Errorf("OutputOffset = %v, want %v", d.OutputOffset(), 5)
+	}
+}
+
+func TestCopyAllocs(t *testing.T) {
+	data := allBytes()
+	encoded := AppendEncode(nil, data)
+
+	// Readers and writers which hide their own WriteTo and ReadFrom methods,
+	// so that io.Copy uses those of the encoder and decoder.
+	src := bytes.NewReader(data)
+	var r io.Reader = struct{ io.Reader }{src}
+	encoder := NewEncoder(io.Discard)
+	encode := func() {
+		io.Copy(encoder, r)
+		src.Reset(data)
+		encoder.Reset(io.Discard)
+	}
+
+	encodedSrc := bytes.NewReader(encoded)
+	decoder := NewDecoder(encodedSrc)
+	var w io.Writer = struct{ io.Writer }{io.Discard}
+	decode := func() {
+		io.Copy(w, decoder)
+		encodedSrc.Reset(encoded)
+		decoder.Reset(encodedSrc)
+	}
+
+	for name, f := range map[string]func(){"Encoder.ReadFrom": encode, "Decoder.WriteTo": decode} {
+		f() // once to allocate the buffers, which Reset keeps
+		if allocs := testing.AllocsPerRun(10, f); allocs != 0 {
+			t.Errorf("%s allocs = %v after Reset, want 0", name, allocs)
+		}
+	}
+}
+
+func TestDecoderErr(t *testing.T) {
+	testcases := []struct {
+		name       string
+		text       string
+		wantErr    error
+		wantOffset int64
+	}{
+		{"eof", "👟👜👣👣👦", nil, 20},
+		{"corrupt", "👟👜garbage", CorruptInputError{Offset: 8, Index: 2}, 8},
+		{"truncated", "👟👜\xf0\x9f", TruncatedInputError(2), 8},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewStrictDecoder(strings.NewReader(tc.text))
+			io.Copy(io.Discard, d)
+			if err := d.Err(); err != tc.wantErr {
+				t.Errorf("Err = %v, want %v", err, tc.wantErr)
 			}
-			if !bytes.Equal(dst, wantDst) {
-				t.Errorf("dst = %v, want %v", dst, wantDst)
+			if got := d.InputOffset(); got != tc.wantOffset {
+				t.Errorf("InputOffset = %v, want %v", got, tc.wantOffset)
 			}
 		})
 	}
 
-	// expect an error when dst too small
-	t.Run("dst too small", func(t *testing.T) {
-		const deficitBytes = 1
-		src := samplecases[0].text
-		dst := make([]byte, DecodedLen(len(src))-deficitBytes) // too small
-		_, err := Decode(dst, src)
-		if err == nil {
-			t.Fatalf("expected error != nil, got %v", err)
+	t.Run("reader error", func(t *testing.T) {
+		wantErr := errors.New("upstream")
+		d := NewDecoder(io.MultiReader(strings.NewReader("👟👜"), iotest.ErrReader(wantErr)))
+		p := make([]byte, 1)
+		if _, err := d.Read(p); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if err := d.Err(); err != nil {
+			t.Errorf("Err = %v with decoded data still buffered", err)
+		}
+		io.Copy(io.Discard, d)
+		if err := d.Err(); err != wantErr {
+			t.Errorf("Err = %v, want %v", err, wantErr)
 		}
 	})
 }
 
-func TestDecodeString(t *testing.T) {
-	// handle all "normal" sample cases
-	for n, sc := range samplecases {
-		t.Run(fmt.Sprintf("sample%02d", n), func(t *testing.T) {
-			src := string(sc.text)
-			got, err := DecodeString(src)
-			if wantErr := false; (err != nil) != wantErr {
-				t.Errorf("err = %v, wantError: %v", err, wantErr)
+// stallReader returns the data of r, then no data and no error forever.
+type stallReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	s.reads++
+	n, err := s.r.Read(p)
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func TestDecoderNoProgress(t *testing.T) {
+	for _, size := range []int{1, largeReadSize} {
+		t.Run(fmt.Sprintf("p=%d", size), func(t *testing.T) {
+			r := &stallReader{r: strings.NewReader("👟👜👣👣👦")}
+			d := NewDecoder(r)
+			p := make([]byte, size)
+			var out []byte
+			for {
+				n, err := d.Read(p)
+				out = append(out, p[:n]...)
+				if err != nil {
+					if err != io.ErrNoProgress {
+						t.Errorf("Read() error = %v, want %v", err, io.ErrNoProgress)
+					}
+					break
+				}
+				if r.reads > 1000 {
+					t.Fatal("Read() never gave up on a reader making no progress")
+				}
 			}
-			if want := sc.data; !bytes.Equal(got, want) {
-				t.Errorf("result = %v, want %v", got, want)
+			if string(out) != "hello" {
+				t.Errorf("Read() = %q before giving up, want %q", out, "hello")
 			}
 		})
 	}
-}
 
-var (
-	encoderDecoderMults = []int{1, 8, 128, 192}
-)
+	t.Run("WriteTo", func(t *testing.T) {
+		r := &stallReader{r: strings.NewReader("👟👜👣👣👦")}
+		var out bytes.Buffer
+		n, err := NewDecoder(r).WriteTo(&out)
+		if err != io.ErrNoProgress {
+			t.Errorf("WriteTo() error = %v, want %v", err, io.ErrNoProgress)
+		}
+		if n != 5 || out.String() != "hello" {
+			t.Errorf("WriteTo() = %d, %q before giving up, want 5, %q", n, out.String(), "hello")
+		}
+		if r.reads > 1000 {
+			t.Errorf("WriteTo() made %d reads before giving up", r.reads)
+		}
+	})
+}
 
-func TestEncoder(t *testing.T) {
-	for _, mult := range encoderDecoderMults {
-		for j, sc := range samplecases {
-			t.Run(fmt.Sprintf("mult%03d/sample%02d", mult, j), func(t *testing.T) {
-				decoded := bytes.Repeat(sc.data, mult)
-				encoded := bytes.Repeat(sc.text, mult)
+// writeChunks writes src to w in chunks of size n, then closes it.
+func writeChunks(w io.WriteCloser, src []byte, n int) error {
+	for len(src) > 0 {
+		chunk := src[:min(n, len(src))]
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		src = src[len(chunk):]
+	}
+	return w.Close()
+}
 
+func TestDecodingWriter(t *testing.T) {
+	data := allBytes()
+	encodings := []struct {
+		name string
+		enc  *Encoding
+	}{
+		{"std", StdEncoding},
+		{"strict", StdEncoding.Strict()},
+		{"skin tone safe", SkinToneSafeEncoding},
+		{"presentation", StdEncoding.EmojiPresentation().WithWrap(13, CRLF)},
+	}
+	for _, e := range encodings {
+		text := make([]byte, e.enc.EncodedLen(len(data)))
+		text = text[:e.enc.Encode(text, data)]
+		// chunk sizes which split runes (and line endings) in every way
+		for _, size := range []int{1, 2, 3, 5, 7, 4096} {
+			t.Run(fmt.Sprintf("%s/chunk%d", e.name, size), func(t *testing.T) {
 				var buf bytes.Buffer
-				enc := NewEncoder(&buf)
-				n, err := enc.Write(decoded)
-				if n != len(decoded) {
-					t.Errorf("n = %d, want %d", n, len(decoded))
-				}
-				if err != nil {
-					t.Errorf("got error: %v", err)
+				if err := writeChunks(e.enc.NewDecodingWriter(&buf), text, size); err != nil {
+					t.Fatalf("got error: %v", err)
 				}
-				if got := buf.Bytes(); !bytes.Equal(encoded, got) {
-					t.Errorf("want %x got %x", encoded, got)
+				if !bytes.Equal(buf.Bytes(), data) {
+					t.Errorf("want %q got %q", data, buf.Bytes())
 				}
 			})
 		}
 	}
 }
 
-func TestDecoder(t *testing.T) {
-	for _, mult := range encoderDecoderMults {
-		for j, sc := range samplecases {
-			t.Run(fmt.Sprintf("mult%03d/sample%02d", mult, j), func(t *testing.T) {
-				decoded := bytes.Repeat(sc.data, mult)
-				encoded := bytes.Repeat(sc.text, mult)
+func TestDecodingWriterErrors(t *testing.T) {
+	const numRunes = 1000
+	valid := bytes.Repeat([]byte("👫"), numRunes)
 
+	testcases := []struct {
+		name    string
+		enc     *Encoding
+		text    []byte
+		wantErr error
+	}{
+		{"corrupt", strictEncoding, slices.Concat(valid, []byte("hello")), CorruptInputError{Offset: 4 * numRunes, Index: numRunes}},
+		{"truncated", strictEncoding, slices.Concat(valid, []byte{0xf0, 0x9f}), TruncatedInputError(2)},
+		{"truncated nonstrict", StdEncoding, slices.Concat(valid, []byte("hi")), TruncatedInputError(2)},
+	}
+	for _, tc := range testcases {
+		for _, size := range []int{1, 3, 4096} {
+			t.Run(fmt.Sprintf("%s/chunk%d", tc.name, size), func(t *testing.T) {
 				var buf bytes.Buffer
-				dec := NewDecoder(bytes.NewReader(encoded))
-				n, err := io.Copy(&buf, dec)
-				if n != int64(len(decoded)) {
-					t.Errorf("n = %d, want %d", n, len(decoded))
-				}
-				if err != nil {
-					t.Errorf("got error: %v", err)
+				err := writeChunks(tc.enc.NewDecodingWriter(&buf), tc.text, size)
+				if err != tc.wantErr {
+					t.Errorf("err = %v, want %v", err, tc.wantErr)
 				}
-				if !bytes.Equal(decoded, buf.Bytes()) {
-					t.Errorf("want %q got %q", decoded, buf.Bytes())
+				if buf.Len() != numRunes {
+					t.Errorf("decoded %v bytes, want %v", buf.Len(), numRunes)
 				}
 			})
 		}
 	}
+
+	t.Run("short count", func(t *testing.T) {
+		text := slices.Concat(valid[:8], []byte("hello"))
+		n, err := strictEncoding.NewDecodingWriter(io.Discard).Write(text)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if n != 8 {
+			t.Errorf("n = %v, want %v", n, 8)
+		}
+	})
+
+	t.Run("sticky", func(t *testing.T) {
+		w := NewDecodingWriter(io.Discard)
+		w.Write([]byte{0xf0, 0x9f})
+		if err := w.Close(); err != TruncatedInputError(2) {
+			t.Fatalf("err = %v, want %v", err, TruncatedInputError(2))
+		}
+		if _, err := w.Write(valid); err != TruncatedInputError(2) {
+			t.Errorf("err = %v, want %v", err, TruncatedInputError(2))
+		}
+	})
+
+	t.Run("downstream error", func(t *testing.T) {
+		wantErr := errors.New("downstream")
+		w := NewDecodingWriter(errWriter{wantErr})
+		if n, err := w.Write(valid); n != 0 || err != wantErr {
+			t.Errorf("n, err = %v, %v, want 0, %v", n, err, wantErr)
+		}
+	})
+
+	t.Run("short write", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewDecodingWriter(shortWriter{&buf})
+		if n, err := w.Write([]byte("👟👜👣👣👦")); n != 16 || err != io.ErrShortWrite {
+			t.Errorf("n, err = %v, %v, want 16, %v", n, err, io.ErrShortWrite)
+		}
+		if err := w.Close(); err != io.ErrShortWrite {
+			t.Errorf("Close() = %v, want %v", err, io.ErrShortWrite)
+		}
+		if got := buf.String(); got != "hell" {
+			t.Errorf("wrote %q, want %q", got, "hell")
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		// Picking up from p[n:] with a fresh writer neither loses nor repeats
+		// anything, however the failed writes cut the input.
+		data := bytes.Repeat(allBytes(), 3)
+		src := StdEncoding.WithWrap(7, CRLF).AppendEncode(nil, data)
+		for _, limit := range []int{1, 100, 255} {
+			var buf bytes.Buffer
+			for p := src; len(p) > 0; {
+				w := NewDecodingWriter(&limitWriter{w: &buf, limit: limit})
+				n, err := w.Write(p)
+				if err == nil {
+					err = w.Close()
+				}
+				if err != nil && err != io.ErrShortWrite {
+					t.Fatalf("limit %d: Write() = %v", limit, err)
+				}
+				if n == 0 && err != nil {
+					t.Fatalf("limit %d: Write() made no progress", limit)
+				}
+				p = p[n:]
+			}
+			if !bytes.Equal(buf.Bytes(), data) {
+				t.Errorf("limit %d: wrote %d bytes, want %d bytes of the input", limit, buf.Len(), len(data))
+			}
+		}
+	})
+}
+
+// errWriter is an io.Writer which always fails with err.
+type errWriter struct{ err error }
+
+func (w errWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+// limitWriter writes at most limit bytes of each write to the underlying
+// writer, and returns err, if any, whenever it writes less than asked.
+type limitWriter struct {
+	w     io.Writer
+	limit int
+	err   error
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.limit {
+		return w.w.Write(p)
+	}
+	n, err := w.w.Write(p[:w.limit])
+	if err == nil {
+		err = w.err
+	}
+	return n, err
+}
+
+// shortWriter writes all but the last byte of each write to the underlying
+// writer, yet reports no error, as a misbehaving io.Writer might.
+type shortWriter struct{ w io.Writer }
+
+func (w shortWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return w.w.Write(p[:len(p)-1])
 }
 
 var (
@@ -181,6 +1723,55 @@ func BenchmarkDecode(b *testing.B) {
 	}
 }
 
+func BenchmarkDecodeStrict(b *testing.B) {
+	src := benchtext
+	dst := make([]byte, DecodedLen(len(src)))
+	b.SetBytes(int64(DecodedLen(len(src))))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = DecodeStrict(dst, src)
+	}
+}
+
+// benchLarge is the input size for the Large benchmarks, which measure the
+// bulk throughput of the kernels rather than per call overhead.
+const benchLarge = 1024 * 1024 // 1 MiB
+
+func BenchmarkEncodeLarge(b *testing.B) {
+	src := make([]byte, benchLarge)
+	_, _ = rand.Read(src)
+	dst := make([]byte, EncodedLen(len(src)))
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Encode(dst, src)
+	}
+}
+
+func BenchmarkDecodeLarge(b *testing.B) {
+	data := make([]byte, benchLarge)
+	_, _ = rand.Read(data)
+	src := make([]byte, EncodedLen(len(data)))
+	Encode(src, data)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Decode(data, src)
+	}
+}
+
+func BenchmarkDecodeStrictLarge(b *testing.B) {
+	data := make([]byte, benchLarge)
+	_, _ = rand.Read(data)
+	src := make([]byte, EncodedLen(len(data)))
+	Encode(src, data)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = DecodeStrict(data, src)
+	}
+}
+
 func BenchmarkDecodeString(b *testing.B) {
 	src := string(benchtext)
 	b.SetBytes(int64(DecodedLen(len(src))))
@@ -216,10 +1807,138 @@ func BenchmarkDecoder(b *testing.B) {
 	Encode(encoded, data)
 
 	src := bytes.NewReader(encoded)
+	decoder := NewDecoder(src)
+	run := func() {
+		io.Copy(io.Discard, decoder)
+		src.Reset(encoded)
+		decoder.Reset(src)
+	}
+	run() // once to allocate the buffers, which Reset keeps
+	if allocs := testing.AllocsPerRun(10, run); allocs != 0 {
+		b.Fatalf("allocs = %v, want 0", allocs)
+	}
+
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		decoder := NewDecoder(src)
-		io.Copy(io.Discard, decoder)
+		run()
+	}
+}
+
+// BenchmarkDecoderLargeRead reads from a Decoder into a 1 MiB p, for
+// comparison with the in-memory BenchmarkDecode. Besides throughput, it
+// reports the number of reads made from the underlying reader.
+func BenchmarkDecoderLargeRead(b *testing.B) {
+	const bytesPerIter = 1024 * 1024 // 1 MiB
+	b.SetBytes(bytesPerIter)
+
+	data := make([]byte, bytesPerIter)
+	_, _ = rand.Read(data)
+	encoded := make([]byte, EncodedLen(len(data)))
+	Encode(encoded, data)
+
+	src := bytes.NewReader(encoded)
+	counter := &callCounter{r: src}
+	decoder := NewDecoder(counter)
+	p := make([]byte, bytesPerIter)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for {
+			if _, err := decoder.Read(p); err != nil {
+				break
+			}
+		}
 		src.Reset(encoded)
+		decoder.Reset(counter)
+	}
+	b.ReportMetric(float64(counter.calls)/float64(b.N), "reads/op")
+}
+
+// callCounter counts the calls to its Read and Write methods, each of which
+// would be a syscall when reading from a file or writing to a socket.
+type callCounter struct {
+	r     io.Reader
+	w     io.Writer
+	calls int
+}
+
+func (c *callCounter) Read(p []byte) (int, error) {
+	c.calls++
+	return c.r.Read(p)
+}
+
+func (c *callCounter) Write(p []byte) (int, error) {
+	c.calls++
+	return c.w.Write(p)
+}
+
+// BenchmarkEncoderCopy compares io.Copy into an Encoder through its Write
+// method, as before it implemented io.ReaderFrom, against through ReadFrom.
+// Besides throughput, it reports the number of writes made to the underlying
+// writer.
+func BenchmarkEncoderCopy(b *testing.B) {
+	const bytesPerIter = 1024 * 1024 // 1 MiB
+	data := make([]byte, bytesPerIter)
+	_, _ = rand.Read(data)
+
+	modes := []struct {
+		name string
+		wrap func(*Encoder) io.Writer
+	}{
+		{"Write", func(e *Encoder) io.Writer { return struct{ io.Writer }{e} }},
+		{"ReadFrom", func(e *Encoder) io.Writer { return e }},
+	}
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			b.SetBytes(bytesPerIter)
+			src := bytes.NewReader(data)
+			dst := &callCounter{w: io.Discard}
+			encoder := NewEncoder(dst)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				io.Copy(mode.wrap(encoder), struct{ io.Reader }{src}) // hide WriteTo
+				src.Reset(data)
+			}
+			b.ReportMetric(float64(dst.calls)/float64(b.N), "writes/op")
+		})
+	}
+}
+
+// BenchmarkDecoderCopy compares copying out of a Decoder through its Read
+// method with a buffer smaller than largeReadSize, as io.Copy did before the
+// decoder implemented io.WriterTo or read in place, against Read with a buffer
+// large enough to decode in place, and against WriteTo. Besides throughput, it
+// reports the number of reads made from the underlying reader.
+func BenchmarkDecoderCopy(b *testing.B) {
+	const bytesPerIter = 1024 * 1024 // 1 MiB
+	data := make([]byte, bytesPerIter)
+	_, _ = rand.Read(data)
+	encoded := make([]byte, EncodedLen(len(data)))
+	Encode(encoded, data)
+
+	modes := []struct {
+		name    string
+		bufSize int // of the io.CopyBuffer buffer, unused by WriteTo
+		wrap    func(*Decoder) io.Reader
+	}{
+		{"Read", largeReadSize / 2, func(d *Decoder) io.Reader { return struct{ io.Reader }{d} }},
+		{"ReadInPlace", copyBufferSize, func(d *Decoder) io.Reader { return struct{ io.Reader }{d} }},
+		{"WriteTo", copyBufferSize, func(d *Decoder) io.Reader { return d }},
+	}
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			b.SetBytes(bytesPerIter)
+			src := bytes.NewReader(encoded)
+			counter := &callCounter{r: src}
+			decoder := NewDecoder(counter)
+			buf := make([]byte, mode.bufSize)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				io.CopyBuffer(struct{ io.Writer }{io.Discard}, mode.wrap(decoder), buf) // hide ReadFrom
+				src.Reset(encoded)
+				decoder.Reset(counter)
+			}
+			b.ReportMetric(float64(counter.calls)/float64(b.N), "reads/op")
+		})
 	}
 }
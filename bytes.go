@@ -0,0 +1,104 @@
+package base100
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// Bytes is a byte slice which is represented as base100 text when marshaled,
+// such as to JSON, XML or TOML, when used with flag.TextVar, when stored in a
+// database, or when printed or logged. Unmarshaling and scanning are strict, in
+// the manner of DecodeStrict.
+type Bytes []byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (b Bytes) MarshalText() ([]byte, error) {
+	return AppendEncode(nil, b), nil
+}
+
+// AppendText implements encoding.TextAppender, appending the encoding of b to
+// dst.
+func (b Bytes) AppendText(dst []byte) ([]byte, error) {
+	return AppendEncode(dst, b), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It decodes text into b,
+// reusing its capacity where there is enough. Empty text leaves b empty, but
+// not nil.
+func (b *Bytes) UnmarshalText(text []byte) error {
+	decoded, err := strictEncoding.AppendDecode((*b)[:0], text)
+	if err != nil {
+		return err
+	}
+	if decoded == nil {
+		decoded = []byte{} // empty rather than nil, as encoding/json would
+	}
+	*b = decoded
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding b as a JSON string, or null
+// if b is nil, as encoding/json does for a plain []byte.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	if b == nil {
+		return []byte("null"), nil
+	}
+	// There is nothing in an encoding which needs escaping.
+	dst := make([]byte, 0, EncodedLen(len(b))+2)
+	dst = append(dst, '"')
+	dst = AppendEncode(dst, b)
+	return append(dst, '"'), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A JSON null leaves b unchanged.
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	// Only unquote the hard way if there are escapes to undo.
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' && bytes.IndexByte(data, '\\') < 0 {
+		return b.UnmarshalText(data[1 : len(data)-1])
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// String returns the base100 encoding of b.
+func (b Bytes) String() string {
+	return EncodeToString(b)
+}
+
+// Format implements fmt.Formatter. The %s and %v verbs print the base100
+// encoding of b, and %q prints it quoted, while %x and %X print b in hex, as
+// they would a plain []byte. Flags, width and precision apply as usual.
+func (b Bytes) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 's', 'v', 'q':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), b.String())
+	case 'x', 'X':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), []byte(b))
+	default:
+		fmt.Fprintf(f, "%%!%c(base100.Bytes=%s)", verb, b.String())
+	}
+}
+
+// LogValue implements slog.LogValuer, logging b as its base100 encoding.
+func (b Bytes) LogValue() slog.Value {
+	return slog.StringValue(b.String())
+}
+
+// ReplaceAttr is for use as slog.HandlerOptions.ReplaceAttr, to log every
+// []byte attribute as its base100 encoding, as if it were a Bytes.
+func ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindAny {
+		if b, ok := a.Value.Any().([]byte); ok {
+			a.Value = Bytes(b).LogValue()
+		}
+	}
+	return a
+}
@@ -0,0 +1,193 @@
+package base100
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestBytesJSON(t *testing.T) {
+	type record struct {
+		Key  Bytes `json:"key"`
+		Salt Bytes `json:"salt"`
+		None Bytes `json:"none"`
+	}
+	in := record{Key: Bytes("hello"), Salt: Bytes{}}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"key":"👟👜👣👣👦","salt":"","none":null}`; got != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+
+	var out record
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Key, in.Key) || out.Salt == nil || len(out.Salt) != 0 || out.None != nil {
+		t.Errorf("Unmarshal() = %#v, want %#v", out, in)
+	}
+	if again, err := json.Marshal(out); err != nil || !bytes.Equal(again, data) {
+		t.Errorf("Marshal() after round trip = %s, %v, want %s", again, err, data)
+	}
+
+	// Escaped, as some other encoder might write it.
+	if err := json.Unmarshal([]byte(`{"key":"\ud83d\udc5f\ud83d\udc5c\ud83d\udc63\ud83d\udc63\ud83d\udc66"}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out.Key), "hello"; got != want {
+		t.Errorf("Unmarshal() of escaped string = %q, want %q", got, want)
+	}
+
+	out.Key = Bytes("unchanged")
+	if err := json.Unmarshal([]byte(`{"key":null}`), &out); err != nil || string(out.Key) != "unchanged" {
+		t.Errorf("Unmarshal() of null = %q, %v, want unchanged", out.Key, err)
+	}
+}
+
+func TestBytesJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not base100", `{"key":"hello"}`},
+		{"truncated", `{"key":"👟👜\xf0\x9f"}`},
+		{"not a string", `{"key":[1,2,3]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out struct{ Key Bytes }
+			if err := json.Unmarshal([]byte(tt.data), &out); err == nil {
+				t.Errorf("Unmarshal(%s) succeeded with %q, want an error", tt.data, out.Key)
+			}
+		})
+	}
+
+	var b Bytes
+	var corrupt CorruptInputError
+	if err := b.UnmarshalText([]byte("👟👜x👣👦")); !errors.As(err, &corrupt) {
+		t.Errorf("UnmarshalText() = %v, want a CorruptInputError", err)
+	}
+}
+
+func TestBytesText(t *testing.T) {
+	b := Bytes("hello")
+	text, err := b.MarshalText()
+	if err != nil || string(text) != "👟👜👣👣👦" {
+		t.Errorf("MarshalText() = %q, %v", text, err)
+	}
+	appended, err := b.AppendText([]byte("key="))
+	if err != nil || string(appended) != "key=👟👜👣👣👦" {
+		t.Errorf("AppendText() = %q, %v", appended, err)
+	}
+
+	// Decoding reuses the existing capacity.
+	buf := make(Bytes, 10, 64)
+	orig := &buf[0]
+	if err := buf.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello" || &buf[0] != orig {
+		t.Errorf("UnmarshalText() = %q, reallocated: %v", buf, &buf[0] != orig)
+	}
+
+	type doc struct {
+		Key Bytes `xml:"key,attr"`
+	}
+	data, err := xml.Marshal(doc{Key: b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out doc
+	if err := xml.Unmarshal(data, &out); err != nil || !bytes.Equal(out.Key, b) {
+		t.Errorf("XML round trip of %s = %q, %v", data, out.Key, err)
+	}
+}
+
+func TestBytesFlag(t *testing.T) {
+	var key Bytes
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.TextVar(&key, "key", Bytes("default"), "key to use")
+	if got, want := fs.Lookup("key").DefValue, "👛👜👝👘👬👣👫"; got != want {
+		t.Errorf("DefValue = %q, want %q", got, want)
+	}
+	if string(key) != "default" {
+		t.Errorf("key = %q before parsing, want %q", key, "default")
+	}
+	if err := fs.Parse([]string{"-key", "👟👜👣👣👦"}); err != nil {
+		t.Fatal(err)
+	}
+	if string(key) != "hello" {
+		t.Errorf("key = %q, want %q", key, "hello")
+	}
+
+	fs.SetOutput(new(bytes.Buffer))
+	if err := fs.Parse([]string{"-key", "hello"}); err == nil {
+		t.Error("Parse() of invalid base100 succeeded, want an error")
+	}
+}
+
+func TestBytesFormat(t *testing.T) {
+	b := Bytes("hi")
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%s", "👟👠"},
+		{"%v", "👟👠"},
+		{"%+v", "👟👠"},
+		{"%q", `"👟👠"`},
+		{"%x", "6869"},
+		{"%X", "6869"},
+		{"% x", "68 69"},
+		{"%#x", "0x6869"},
+		{"%10s", "        👟👠"},
+		{"%-4s|", "👟👠  |"},
+		{"%.1s", "👟"},
+		{"%d", "%!d(base100.Bytes=👟👠)"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, b); got != tt.want {
+			t.Errorf("Sprintf(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+	if got, want := fmt.Sprint(struct{ Key Bytes }{b}), "{👟👠}"; got != want {
+		t.Errorf("Sprint() of struct = %q, want %q", got, want)
+	}
+}
+
+func TestBytesLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return ReplaceAttr(groups, a)
+		},
+	}))
+	logger.Info("keys",
+		"wrapped", Bytes("hi"),
+		"raw", []byte("hi"),
+		slog.Group("g", "nested", []byte("hi")),
+		"other", 42,
+	)
+	want := "level=INFO msg=keys wrapped=👟👠 raw=👟👠 g.nested=👟👠 other=42\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output = %q, want %q", got, want)
+	}
+
+	// Without ReplaceAttr, only Bytes are encoded.
+	buf.Reset()
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("keys", "wrapped", Bytes("hi"))
+	if !strings.Contains(buf.String(), `"wrapped":"👟👠"`) {
+		t.Errorf("log output = %q, want it to contain the encoding", buf.String())
+	}
+}
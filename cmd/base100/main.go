@@ -18,6 +18,9 @@ const (
 
 type options struct {
 	decode        bool   // decode input instead of encode
+	strict        bool   // validate input when decoding
+	wrap          int    // wrap encoded lines after this many runes, 0 to disable
+	jobs          int    // number of goroutines to work on, 0 for one per CPU
 	input, output string // optional file paths
 }
 
@@ -31,6 +34,9 @@ USAGE:
 
 FLAGS:
     -d, --decode     Decodes input
+    -s, --strict     Rejects invalid input when decoding
+    -w, --wrap       Wraps encoded lines after COLS emoji (default 0, no wrapping)
+    -j, --jobs       Spreads work across N cores (default 1, 0 for one per CPU)
     -i, --input      Input file (default use STDIN)
     -o, --output     Output file (default use STDOUT)
     -h, --help       Prints help information
@@ -40,12 +46,26 @@ FLAGS:
 	const nodesc = "" // descriptions not shown since we override flag.Usage
 	flag.BoolVar(&opts.decode, "decode", false, nodesc)
 	flag.BoolVar(&opts.decode, "d", false, nodesc)
+	flag.BoolVar(&opts.strict, "strict", false, nodesc)
+	flag.BoolVar(&opts.strict, "s", false, nodesc)
+	flag.IntVar(&opts.wrap, "wrap", 0, nodesc)
+	flag.IntVar(&opts.wrap, "w", 0, nodesc)
+	flag.IntVar(&opts.jobs, "jobs", 1, nodesc)
+	flag.IntVar(&opts.jobs, "j", 1, nodesc)
 	flag.StringVar(&opts.input, "input", "", nodesc)
 	flag.StringVar(&opts.input, "i", "", nodesc)
 	flag.StringVar(&opts.output, "output", "", nodesc)
 	flag.StringVar(&opts.output, "o", "", nodesc)
 
 	flag.Parse()
+	if opts.wrap < 0 {
+		fmt.Fprintf(os.Stderr, "invalid wrap size: %d\n", opts.wrap)
+		os.Exit(2)
+	}
+	if opts.jobs < 0 {
+		fmt.Fprintf(os.Stderr, "invalid number of jobs: %d\n", opts.jobs)
+		os.Exit(2)
+	}
 	return
 }
 
@@ -77,17 +97,31 @@ func main() {
 	writer := bufio.NewWriterSize(out, bufsize)
 	defer writer.Flush()
 
+	enc := base100.StdEncoding
+	if opts.strict {
+		enc = enc.Strict()
+	}
+
 	if opts.decode {
-		// decoder currently can die due to lack of CRLF filtering
-		decoder := base100.NewDecoder(reader)
+		var decoder io.Reader = enc.NewDecoder(reader)
+		if opts.jobs != 1 {
+			decoder = enc.NewParallelDecoder(reader, opts.jobs)
+		}
 		_, err := io.Copy(writer, decoder)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "FATAL: %v\n", err)
 			os.Exit(1)
 		}
 	} else {
-		encoder := base100.NewEncoder(writer)
+		enc = enc.WithWrap(opts.wrap, base100.LF)
+		var encoder io.WriteCloser = enc.NewEncoder(writer)
+		if opts.jobs != 1 {
+			encoder = enc.NewParallelEncoder(writer, opts.jobs)
+		}
 		_, err := io.Copy(encoder, reader)
+		if err == nil {
+			err = encoder.Close()
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "FATAL: %v\n", err)
 			os.Exit(1)
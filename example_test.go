@@ -2,7 +2,12 @@ package base100_test
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"os"
+	"strings"
+	"testing/fstest"
 
 	"github.com/mroth/base100-go"
 )
@@ -38,3 +43,83 @@ func ExampleDecodeString() {
 	fmt.Printf("%s", result)
 	// Output: the quick brown fox jumped over the lazy dog
 }
+
+func ExampleNewWrappingEncoder() {
+	src := []byte("the quick brown fox jumped over the lazy dog\n")
+	encoder := base100.NewWrappingEncoder(os.Stdout, 16, base100.LF)
+	encoder.Write(src)
+	encoder.Close() // must close the encoder to terminate the last line
+	// Output:
+	// 👫👟👜🐗👨👬👠👚👢🐗👙👩👦👮👥🐗
+	// 👝👦👯🐗👡👬👤👧👜👛🐗👦👭👜👩🐗
+	// 👫👟👜🐗👣👘👱👰🐗👛👦👞🐁
+}
+
+func ExampleEncoding_Strict() {
+	enc := base100.StdEncoding.Strict()
+	_, err := enc.DecodeString("👫👟👜 héllo")
+	fmt.Println(err)
+	// Output: base100: illegal data at input byte 13 (rune 4)
+}
+
+func ExampleAppendEncode() {
+	buf := []byte("payload: ")
+	buf = base100.AppendEncode(buf, []byte("hello"))
+	fmt.Printf("%s", buf)
+	// Output: payload: 👟👜👣👣👦
+}
+
+func ExampleEncoding_SkinToneSafe() {
+	src := []byte{0x01, 0x04, 0x05}
+	fmt.Println(base100.StdEncoding.EncodeToString(src))
+	fmt.Println(base100.SkinToneSafeEncoding.EncodeToString(src))
+	// Output:
+	// 🏸🏻🏼
+	// 🏸📷📸
+}
+
+func ExampleNewEncodingReader() {
+	r := base100.NewEncodingReader(strings.NewReader("hello"))
+	fmt.Println(r.Len()) // e.g. for an HTTP Content-Length
+	io.Copy(os.Stdout, r)
+	// Output:
+	// 20
+	// 👟👜👣👣👦
+}
+
+func ExampleNewDecodingWriter() {
+	w := base100.NewDecodingWriter(os.Stdout)
+	encoded := []byte("👟👜👣👣👦")
+	w.Write(encoded[:6]) // chunks need not end on a rune boundary
+	w.Write(encoded[6:])
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// hello
+}
+
+func ExampleNewSectionDecoder() {
+	src := strings.NewReader(base100.EncodeToString([]byte("the quick brown fox jumped over the lazy dog\n")))
+	d := base100.NewSectionDecoder(src, src.Size())
+
+	// Read "fox" straight from the middle, without decoding what comes before.
+	buf := make([]byte, 3)
+	if _, err := d.ReadAt(buf, 16); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s of %d bytes\n", buf, d.Size())
+	// Output: fox of 45 bytes
+}
+
+func ExampleNewDecodingFS() {
+	fsys := fstest.MapFS{
+		"greeting.txt.b100": {Data: base100.AppendEncode(nil, []byte("hello, world\n"))},
+	}
+	data, err := fs.ReadFile(base100.NewDecodingFS(fsys), "greeting.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s", data)
+	// Output: hello, world
+}
@@ -0,0 +1,244 @@
+package base100
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// encodedExt is the extension of the encoded files which a decoding file system
+// presents without it, decoded.
+const encodedExt = ".b100"
+
+// NewDecodingFS returns a file system which presents each regular file
+// name.b100 in fsys as a file called name, decoding its contents on the fly.
+// Everything else in fsys is presented as is, except that a file name.b100
+// hides any file called name in the same directory.
+//
+// The size reported for a decoded file assumes the encoded data is one rune
+// per byte, without wrapping or whitespace, as does seeking within it, which
+// is supported if the underlying file implements io.Seeker. Reading works
+// regardless.
+//
+// The returned file system implements fs.ReadFileFS, fs.ReadDirFS and
+// fs.StatFS, so it can be used with http.FS, template.ParseFS and the like.
+func NewDecodingFS(fsys fs.FS) fs.FS {
+	return StdEncoding.NewDecodingFS(fsys)
+}
+
+// NewDecodingFS returns a file system which decodes files using enc, see the
+// package level NewDecodingFS.
+func (enc *Encoding) NewDecodingFS(fsys fs.FS) fs.FS {
+	return &decodingFS{enc: enc, fsys: fsys}
+}
+
+type decodingFS struct {
+	enc  *Encoding
+	fsys fs.FS
+}
+
+// encodedName returns the name of the encoded file presented as name, if
+// there is one. Failing to stat it, for whatever reason, means there is not,
+// leaving the error to be reported for name itself.
+func (dfs *decodingFS) encodedName(name string) (string, bool) {
+	if name == "." {
+		return "", false
+	}
+	info, err := fs.Stat(dfs.fsys, name+encodedExt)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return name + encodedExt, true
+}
+
+func (dfs *decodingFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	encoded, ok := dfs.encodedName(name)
+	if !ok {
+		f, err := dfs.fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		if dir, ok := f.(fs.ReadDirFile); ok {
+			return &decodingDir{ReadDirFile: dir}, nil
+		}
+		return f, nil
+	}
+
+	f, err := dfs.fsys.Open(encoded)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &decodedFile{f: f, d: dfs.enc.NewDecoder(f), info: decodedInfo{info}}, nil
+}
+
+func (dfs *decodingFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	encoded, ok := dfs.encodedName(name)
+	if !ok {
+		return fs.ReadFile(dfs.fsys, name)
+	}
+
+	src, err := fs.ReadFile(dfs.fsys, encoded)
+	if err != nil {
+		return nil, err
+	}
+	dst := make([]byte, dfs.enc.DecodedLen(len(src)))
+	n, err := dfs.enc.Decode(dst, src)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return dst[:n], nil
+}
+
+func (dfs *decodingFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	encoded, ok := dfs.encodedName(name)
+	if !ok {
+		return fs.Stat(dfs.fsys, name)
+	}
+	info, err := fs.Stat(dfs.fsys, encoded)
+	if err != nil {
+		return nil, err
+	}
+	return decodedInfo{info}, nil
+}
+
+func (dfs *decodingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(dfs.fsys, name)
+	return decodedEntries(entries), err
+}
+
+// decodedEntries returns entries as presented by a decoding file system, in
+// order, renaming encoded files and leaving out those they hide.
+func decodedEntries(entries []fs.DirEntry) []fs.DirEntry {
+	hidden := make(map[string]bool)
+	for _, e := range entries {
+		if isEncoded(e.Name(), e.Type()) {
+			hidden[strings.TrimSuffix(e.Name(), encodedExt)] = true
+		}
+	}
+	out := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		switch {
+		case isEncoded(e.Name(), e.Type()):
+			out = append(out, decodedEntry{e})
+		case !hidden[e.Name()]:
+			out = append(out, e)
+		}
+	}
+	slices.SortFunc(out, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return out
+}
+
+// isEncoded reports whether a directory entry with the given name and type is
+// an encoded file, to be presented decoded.
+func isEncoded(name string, mode fs.FileMode) bool {
+	return mode.IsRegular() && len(name) > len(encodedExt) && strings.HasSuffix(name, encodedExt)
+}
+
+// decodingDir is a directory of a decoding file system.
+type decodingDir struct {
+	fs.ReadDirFile
+	entries []fs.DirEntry // nil until the first call to ReadDir
+}
+
+// ReadDir implements fs.ReadDirFile. Since an encoded file may hide one which
+// comes before it, the whole directory is read at once, and handed out from
+// memory.
+func (d *decodingDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		entries, err := d.ReadDirFile.ReadDir(-1)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = decodedEntries(entries)
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = d.entries[len(d.entries):]
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	entries := d.entries[:min(n, len(d.entries))]
+	d.entries = d.entries[len(entries):]
+	return entries, nil
+}
+
+// decodedFile is an encoded file of a decoding file system, open for reading
+// its decoded contents.
+type decodedFile struct {
+	f    fs.File
+	d    *Decoder
+	info decodedInfo
+	base int64 // decoded offset at which d started reading
+}
+
+func (f *decodedFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *decodedFile) Read(p []byte) (int, error) { return f.d.Read(p) }
+func (f *decodedFile) Close() error               { return f.f.Close() }
+
+// Seek implements io.Seeker, if the underlying file does. Offsets are of the
+// decoded contents, and assume one rune per byte of the encoded ones.
+func (f *decodedFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.f.(io.Seeker)
+	if !ok {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.Name(), Err: errors.ErrUnsupported}
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.base + f.d.OutputOffset()
+	case io.SeekEnd:
+		offset += f.info.Size()
+	default:
+		return 0, errWhence
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	if _, err := s.Seek(offset*encodedByteSize, io.SeekStart); err != nil {
+		return 0, err
+	}
+	f.d.Reset(f.f)
+	f.base = offset
+	return offset, nil
+}
+
+// decodedInfo describes an encoded file as presented by a decoding file system.
+type decodedInfo struct{ fs.FileInfo }
+
+func (fi decodedInfo) Name() string   { return strings.TrimSuffix(fi.FileInfo.Name(), encodedExt) }
+func (fi decodedInfo) Size() int64    { return fi.FileInfo.Size() / encodedByteSize }
+func (fi decodedInfo) String() string { return fs.FormatFileInfo(fi) }
+
+// decodedEntry is a directory entry of an encoded file, as presented by a
+// decoding file system.
+type decodedEntry struct{ fs.DirEntry }
+
+func (e decodedEntry) Name() string { return strings.TrimSuffix(e.DirEntry.Name(), encodedExt) }
+
+func (e decodedEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return decodedInfo{info}, nil
+}
+
+func (e decodedEntry) String() string { return fs.FormatDirEntry(e) }
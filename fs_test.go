@@ -0,0 +1,130 @@
+package base100
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+)
+
+func TestDecodingFS(t *testing.T) {
+	data := bytes.Repeat(allBytes(), 10)
+	fsys := fstest.MapFS{
+		"data.bin.b100":       {Data: AppendEncode(nil, data)},
+		"empty.b100":          {Data: nil},
+		"plain.txt":           {Data: []byte("as is")},
+		"hidden":              {Data: []byte("hidden by hidden.b100")},
+		"hidden.b100":         {Data: AppendEncode(nil, []byte("decoded"))},
+		".b100":               {Data: []byte("no name left once decoded")},
+		"dir.b100/file.b100":  {Data: AppendEncode(nil, []byte("in a dir"))},
+		"sub/dir/nested.b100": {Data: AppendEncode(nil, []byte("nested"))},
+	}
+	dfs := NewDecodingFS(fsys)
+
+	if err := fstest.TestFS(dfs, "data.bin", "empty", "plain.txt", "hidden", ".b100", "dir.b100/file", "sub/dir/nested"); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []struct {
+		name string
+		want []byte
+	}{
+		{"data.bin", data},
+		{"empty", []byte{}},
+		{"plain.txt", []byte("as is")},
+		{"hidden", []byte("decoded")},
+		{"dir.b100/file", []byte("in a dir")},
+		{"sub/dir/nested", []byte("nested")},
+	}
+	for _, tt := range files {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fs.ReadFile(dfs, tt.name)
+			if err != nil || !bytes.Equal(got, tt.want) {
+				t.Errorf("ReadFile() = %q, %v, want %q, nil", got, err, tt.want)
+			}
+			info, err := fs.Stat(dfs, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != int64(len(tt.want)) {
+				t.Errorf("Stat().Size() = %d, want %d", info.Size(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestDecodingFSWrapped(t *testing.T) {
+	// Sizes are off, but reading still works.
+	fsys := fstest.MapFS{
+		"wrapped.b100": {Data: StdEncoding.WithWrap(4, LF).AppendEncode(nil, []byte("hello, world"))},
+		"bad.b100":     {Data: []byte("not base100 at all")},
+	}
+	dfs := StdEncoding.Strict().NewDecodingFS(fsys)
+
+	f, err := dfs.Open("wrapped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := io.ReadAll(f)
+	if string(got) != "hello, world" || err != nil {
+		t.Errorf("ReadAll() = %q, %v, want %q, nil", got, err, "hello, world")
+	}
+
+	var corrupt CorruptInputError
+	if _, err := fs.ReadFile(dfs, "bad"); !errors.As(err, &corrupt) {
+		t.Errorf("ReadFile() of corrupt file = %v, want a CorruptInputError", err)
+	}
+}
+
+func TestDecodingFSTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hello.tmpl.b100": {Data: AppendEncode(nil, []byte(`Hello, {{.}}!`))},
+		"other.txt":       {Data: []byte("not a template")},
+	}
+	tmpl, err := template.ParseFS(NewDecodingFS(fsys), "*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err := tmpl.ExecuteTemplate(&buf, "hello.tmpl", "world"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "Hello, world!"; got != want {
+		t.Errorf("template output = %q, want %q", got, want)
+	}
+}
+
+func TestDecodingFSHTTP(t *testing.T) {
+	data := bytes.Repeat(allBytes(), 4)
+	fsys := fstest.MapFS{"static/data.bin.b100": {Data: AppendEncode(nil, data)}}
+	srv := httptest.NewServer(http.FileServer(http.FS(NewDecodingFS(fsys))))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL+"/static/data.bin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Range", "bytes=100-299")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Errorf("status = %s, want %d", resp.Status, http.StatusPartialContent)
+	}
+	if want := data[100:300]; !bytes.Equal(got, want) {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
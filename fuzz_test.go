@@ -5,7 +5,9 @@ package base100
 
 import (
 	"bytes"
+	"io"
 	"testing"
+	"testing/iotest"
 	"unicode/utf8"
 )
 
@@ -25,16 +27,27 @@ func FuzzEncode(f *testing.F) {
 	for _, tc := range fuzzcases {
 		f.Add(tc)
 	}
+	f.Add(allBytes()) // long enough for the assembly kernels
 
 	f.Fuzz(func(t *testing.T, orig []byte) {
 		encoded := make([]byte, EncodedLen(len(orig)))
 		Encode(encoded, orig)
+		if useAVX2 {
+			generic := make([]byte, len(encoded))
+			withoutAVX2(func() { Encode(generic, orig) })
+			if !bytes.Equal(encoded, generic) {
+				t.Errorf("AVX2: %q, generic: %q", encoded, generic)
+			}
+		}
 		if got, want := len(encoded), EncodedLen(len(orig)); got != want {
 			t.Errorf("encoded is %d bytes, but EncodedLen predicted %d", got, want)
 		}
 		if !utf8.Valid(encoded) { // encoded version should always be valid utf8
 			t.Errorf("Encode produced invalid UTF-8 %q", encoded)
 		}
+		if !Valid(encoded) {
+			t.Errorf("Encode produced invalid base100 %q", encoded)
+		}
 
 		decoded := make([]byte, DecodedLen(len(encoded)))
 		n, err := Decode(decoded, encoded)
@@ -53,3 +66,89 @@ func FuzzEncode(f *testing.F) {
 		}
 	})
 }
+
+func FuzzDecode(f *testing.F) {
+	var fuzzcases = []string{
+		"👫👟👜🐗👨👬👠👚👢",
+		"👫👟👜\n🐗👨👬\r\n👠👚👢\n",
+		"👫 👟\t👜",
+		"👫👟\xf0\x9f",
+		"👫\ufe0f👟\u200d👜\ufe0e",
+		"📷📸🏻🏼",
+		"héllo",
+		"",
+	}
+
+	// Long enough for the assembly kernels, with trouble part way through.
+	long := EncodeToString(bytes.Repeat(allBytes(), 2))
+	fuzzcases = append(fuzzcases, long, long[:600]+"\n"+long[600:], long[:700]+"x"+long[701:])
+
+	for _, tc := range fuzzcases {
+		f.Add([]byte(tc), uint8(0))
+		f.Add([]byte(tc), uint8(0xff))
+		f.Add([]byte(tc), uint8(1))
+	}
+
+	// Decode and the stream decoder should always agree with each other, no
+	// matter how the input is split across reads, or which options are used.
+	f.Fuzz(func(t *testing.T, src []byte, opts uint8) {
+		enc := StdEncoding
+		if opts&1 != 0 {
+			enc = enc.Strict()
+		}
+		if opts&2 != 0 {
+			enc = enc.WithWhitespace(false)
+		}
+		if opts&4 != 0 {
+			enc = enc.SkinToneSafe()
+		}
+		if opts&8 != 0 {
+			enc = enc.EmojiPresentation()
+		}
+		if opts&16 != 0 {
+			enc = enc.IgnoreFormatChars()
+		}
+
+		dst := make([]byte, enc.DecodedLen(len(src)))
+		n, err := enc.Decode(dst, src)
+		if useAVX2 {
+			generic := make([]byte, len(dst))
+			var genericN int
+			var genericErr error
+			withoutAVX2(func() { genericN, genericErr = enc.Decode(generic, src) })
+			if err != genericErr || !bytes.Equal(dst[:n], generic[:genericN]) {
+				t.Errorf("AVX2: %q, %v, generic: %q, %v", dst[:n], err, generic[:genericN], genericErr)
+			}
+		}
+		if got, want := n, len(dst); got > want {
+			t.Fatalf("Decode wrote %d bytes, but DecodedLen predicted at most %d", got, want)
+		}
+
+		streamed, streamErr := io.ReadAll(enc.NewDecoder(iotest.OneByteReader(bytes.NewReader(src))))
+		if err != streamErr {
+			t.Errorf("Decode error %v, stream decoder error %v", err, streamErr)
+		}
+		if !bytes.Equal(dst[:n], streamed) {
+			t.Errorf("Decode: %q, stream decoder: %q", dst[:n], streamed)
+		}
+
+		var pushed bytes.Buffer
+		if pushErr := writeChunks(enc.NewDecodingWriter(&pushed), src, 3); err != pushErr {
+			t.Errorf("Decode error %v, decoding writer error %v", err, pushErr)
+		}
+		if !bytes.Equal(dst[:n], pushed.Bytes()) {
+			t.Errorf("Decode: %q, decoding writer: %q", dst[:n], pushed.Bytes())
+		}
+		if opts&1 != 0 && enc.Valid(src) != (err == nil) {
+			t.Errorf("Valid = %v, but strict Decode error %v", enc.Valid(src), err)
+		}
+	})
+}
+
+// withoutAVX2 runs f with the assembly kernels turned off, so that the results
+// of the generic Go ones can be compared with them.
+func withoutAVX2(f func()) {
+	defer func(saved bool) { useAVX2 = saved }(useAVX2)
+	useAVX2 = false
+	f()
+}
@@ -0,0 +1,484 @@
+package base100
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Every byte of input is encoded on its own into a rune of fixed size, so work
+// can be split up and spread across goroutines without any coordination, as
+// long as the pieces are put back together in the same order.
+
+// parallelMinChunk is the least amount of input worth handing to a goroutine
+// of its own when working on a buffer in memory.
+const parallelMinChunk = 64 * 1024
+
+// parallelChunkSize is the amount of input each worker of the parallel stream
+// encoder and decoder is handed at a time.
+const parallelChunkSize = 256 * 1024
+
+// numJobs returns jobs, or one per CPU if jobs is zero or less.
+func numJobs(jobs int) int {
+	if jobs <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return jobs
+}
+
+// splitEven returns the offsets at which to split n bytes into at most jobs
+// chunks of at least parallelMinChunk bytes, each a multiple of align, with 0
+// and n at either end.
+func splitEven(n, jobs, align int) []int {
+	size := max((n+jobs-1)/jobs, parallelMinChunk)
+	size = (size + align - 1) / align * align
+	bounds := []int{0}
+	for off := size; off < n; off += size {
+		bounds = append(bounds, off)
+	}
+	return append(bounds, n)
+}
+
+// splitRunes returns the offsets at which to split src into at most jobs
+// chunks for decoding, with 0 and len(src) at either end. Each split is placed
+// just before a rune, so that for well formed input, decoding each chunk on its
+// own comes out the same as decoding all of src in one go.
+func splitRunes(src []byte, jobs int) []int {
+	bounds := []int{0}
+	for _, off := range splitEven(len(src), jobs, 1) {
+		if off <= bounds[len(bounds)-1] {
+			continue
+		}
+		i := bytes.IndexByte(src[off:], fixedByte1)
+		if i < 0 {
+			break
+		}
+		bounds = append(bounds, off+i)
+	}
+	if bounds[len(bounds)-1] != len(src) {
+		bounds = append(bounds, len(src))
+	}
+	return bounds
+}
+
+// EncodeParallel is like Encode, but splits large inputs across jobs
+// goroutines, or one per CPU if jobs is zero or less.
+func EncodeParallel(dst, src []byte, jobs int) {
+	StdEncoding.EncodeParallel(dst, src, jobs)
+}
+
+// EncodeParallel is like Encode, but splits large inputs across jobs
+// goroutines, or one per CPU if jobs is zero or less. The output is the same as
+// that of Encode. It falls back to encoding serially if dst is smaller than
+// EncodedLen(len(src)).
+func (enc *Encoding) EncodeParallel(dst, src []byte, jobs int) int {
+	bounds := splitEven(len(src), numJobs(jobs), max(enc.wrap, 1))
+	if len(bounds) <= 2 || len(dst) < enc.EncodedLen(len(src)) {
+		return enc.Encode(dst, src)
+	}
+
+	// Chunks are split at line boundaries, so EncodedLen gives where each one
+	// starts. Only with variation selectors is it an upper bound, in which
+	// case the output needs squeezing together afterwards.
+	lens := make([]int, len(bounds)-1)
+	var wg sync.WaitGroup
+	for k := range lens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start, end := bounds[k], bounds[k+1]
+			lens[k] = enc.Encode(dst[enc.EncodedLen(start):enc.EncodedLen(end)], src[start:end])
+		}()
+	}
+	wg.Wait()
+
+	var n int
+	for k, m := range lens {
+		n += copy(dst[n:], dst[enc.EncodedLen(bounds[k]):][:m])
+	}
+	return n
+}
+
+// DecodeParallel is like Decode, but splits large inputs across jobs
+// goroutines, or one per CPU if jobs is zero or less.
+func DecodeParallel(dst, src []byte, jobs int) (int, error) {
+	return StdEncoding.DecodeParallel(dst, src, jobs)
+}
+
+// DecodeParallel is like Decode, but splits large inputs across jobs
+// goroutines, or one per CPU if jobs is zero or less. The results are the same
+// as those of Decode. It falls back to decoding serially if dst is smaller than
+// DecodedLen(len(src)), and from the point at which any garbage in the input
+// throws off where runes start.
+func (enc *Encoding) DecodeParallel(dst, src []byte, jobs int) (int, error) {
+	bounds := splitRunes(src, numJobs(jobs))
+	if len(bounds) <= 2 || len(dst) < enc.DecodedLen(len(src)) {
+		return enc.Decode(dst, src)
+	}
+
+	// Each chunk is decoded into its share of dst by input size, and then the
+	// output is squeezed together, in case of whitespace, in order.
+	type result struct {
+		n, nsrc, nskip int
+		err            error
+	}
+	results := make([]result, len(bounds)-1)
+	var wg sync.WaitGroup
+	for k := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start, end := bounds[k], bounds[k+1]
+			r := &results[k]
+			r.n, r.nsrc, r.nskip, r.err = enc.decodeChunk(dst[start/encodedByteSize:end/encodedByteSize], src[start:end])
+		}()
+	}
+	wg.Wait()
+
+	var n int
+	var index int64
+	for k, r := range results {
+		start := bounds[k]
+		n += copy(dst[n:], dst[start/encodedByteSize:][:r.n])
+		if r.err != nil {
+			return n, relocate(r.err, int64(start), index)
+		}
+		index += int64(r.n + r.nskip)
+
+		// Anything left over means the next chunk did not start with a rune
+		// after all, or that the input ends with an incomplete one. Decode
+		// can sort out either.
+		if rest := start + r.nsrc; rest != bounds[k+1] {
+			m, err := enc.Decode(dst[n:], src[rest:])
+			return n + m, relocate(err, int64(rest), index)
+		}
+	}
+	return n, nil
+}
+
+// parallelChunk is a unit of work for the parallel stream encoder and decoder,
+// passed to a worker to be processed, and in order to be output.
+type parallelChunk struct {
+	in, out []byte
+	done    chan struct{} // closed when out is ready
+
+	// decoding only
+	n, nsrc, nskip int
+	err            error // from decodeChunk
+	last           bool  // no more input follows
+	readErr        error // from the underlying reader, if last
+}
+
+/* PARALLEL ENCODER */
+
+// NewParallelEncoder returns a stream encoder like NewEncoder, which encodes
+// chunks of its input on jobs goroutines, or one per CPU if jobs is zero or
+// less, while writing the results to w in order. The caller must Close the
+// returned encoder to write out the final chunk and stop the goroutines.
+func NewParallelEncoder(w io.Writer, jobs int) io.WriteCloser {
+	return StdEncoding.NewParallelEncoder(w, jobs)
+}
+
+// NewParallelEncoder returns a stream encoder using enc, see the package level
+// NewParallelEncoder.
+func (enc *Encoding) NewParallelEncoder(w io.Writer, jobs int) io.WriteCloser {
+	jobs = numJobs(jobs)
+	chunkSize := parallelChunkSize
+	if enc.wrap > 0 {
+		// Whole lines only, so that each chunk starts on a new one.
+		chunkSize = max(chunkSize/enc.wrap, 1) * enc.wrap
+	}
+	e := &parallelEncoder{
+		enc:       enc,
+		w:         w,
+		chunkSize: chunkSize,
+		work:      make(chan *parallelChunk, jobs),
+		queue:     make(chan *parallelChunk, jobs),
+		free:      make(chan *parallelChunk, 2*jobs+1),
+		done:      make(chan struct{}),
+	}
+	for range jobs {
+		go e.worker()
+	}
+	go e.writer()
+	return e
+}
+
+type parallelEncoder struct {
+	enc       *Encoding
+	w         io.Writer
+	chunkSize int
+	next      *parallelChunk      // chunk being filled by Write
+	work      chan *parallelChunk // chunks to encode
+	queue     chan *parallelChunk // chunks to write, in order
+	free      chan *parallelChunk // chunks to reuse, once written
+	done      chan struct{}       // closed when the writer goroutine exits
+	closed    bool
+
+	mu  sync.Mutex
+	err error // sticky, from writing to w
+}
+
+func (e *parallelEncoder) Write(p []byte) (n int, err error) {
+	if e.closed {
+		return 0, errClosed
+	}
+	for len(p) > 0 {
+		if err := e.loadErr(); err != nil {
+			return n, err
+		}
+		if e.next == nil {
+			e.next = e.newChunk()
+		}
+		numCopy := min(len(p), e.chunkSize-len(e.next.in))
+		e.next.in = append(e.next.in, p[:numCopy]...)
+		n += numCopy
+		p = p[numCopy:]
+		if len(e.next.in) == e.chunkSize {
+			e.dispatch()
+		}
+	}
+	return n, nil
+}
+
+// Close encodes and writes out any remaining input, then stops the goroutines
+// of the encoder. It does not close the underlying writer.
+func (e *parallelEncoder) Close() error {
+	if e.closed {
+		return e.loadErr()
+	}
+	e.closed = true
+	if e.next != nil && len(e.next.in) > 0 {
+		e.dispatch()
+	}
+	close(e.work)
+	close(e.queue)
+	<-e.done
+	return e.loadErr()
+}
+
+func (e *parallelEncoder) newChunk() *parallelChunk {
+	select {
+	case c := <-e.free:
+		c.in = c.in[:0]
+		c.done = make(chan struct{})
+		return c
+	default:
+		return &parallelChunk{
+			in:   make([]byte, 0, e.chunkSize),
+			out:  make([]byte, e.enc.EncodedLen(e.chunkSize)),
+			done: make(chan struct{}),
+		}
+	}
+}
+
+// dispatch hands the chunk being filled to the workers. The queue fills up
+// when the writer falls behind, holding back the caller from getting too far
+// ahead of it.
+func (e *parallelEncoder) dispatch() {
+	e.queue <- e.next
+	e.work <- e.next
+	e.next = nil
+}
+
+func (e *parallelEncoder) worker() {
+	for c := range e.work {
+		c.out = c.out[:e.enc.Encode(c.out[:cap(c.out)], c.in)]
+		close(c.done)
+	}
+}
+
+func (e *parallelEncoder) writer() {
+	defer close(e.done)
+	for c := range e.queue {
+		<-c.done
+		if e.loadErr() == nil {
+			written, err := e.w.Write(c.out)
+			if err == nil && written < len(c.out) {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				e.mu.Lock()
+				e.err = err
+				e.mu.Unlock()
+			}
+		}
+		select {
+		case e.free <- c:
+		default:
+		}
+	}
+}
+
+func (e *parallelEncoder) loadErr() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+/* PARALLEL DECODER */
+
+// errClosed is returned when using a parallel stream encoder or decoder after
+// it has been closed.
+var errClosed = errors.New("base100: use of closed parallel encoder or decoder")
+
+// NewParallelDecoder returns a stream decoder like NewDecoder, which reads
+// ahead of the caller, and decodes chunks of its input on jobs goroutines, or
+// one per CPU if jobs is zero or less. The decoded data is returned in order,
+// the same as that of NewDecoder. The goroutines stop by themselves at the end
+// of the input or on error; to stop them any sooner, Close the decoder.
+func NewParallelDecoder(r io.Reader, jobs int) io.ReadCloser {
+	return StdEncoding.NewParallelDecoder(r, jobs)
+}
+
+// NewParallelDecoder returns a stream decoder using enc, see the package level
+// NewParallelDecoder.
+func (enc *Encoding) NewParallelDecoder(r io.Reader, jobs int) io.ReadCloser {
+	jobs = numJobs(jobs)
+	d := &parallelDecoder{
+		enc:   enc,
+		work:  make(chan *parallelChunk, jobs),
+		queue: make(chan *parallelChunk, jobs),
+		quit:  make(chan struct{}),
+	}
+	for range jobs {
+		go d.worker()
+	}
+	go d.reader(r)
+	return d
+}
+
+type parallelDecoder struct {
+	enc   *Encoding
+	work  chan *parallelChunk // chunks to decode
+	queue chan *parallelChunk // chunks to return, in order
+	quit  chan struct{}       // closed by Close
+
+	// used by Read only
+	out   []byte // decoded output not yet returned
+	carry []byte // input left over from the previous chunk
+	off   int64  // input offset of the current chunk, for error reporting
+	index int64  // input rune index of the current chunk, for error reporting
+	err   error
+}
+
+func (d *parallelDecoder) Read(p []byte) (n int, err error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		select {
+		case c := <-d.queue:
+			select {
+			case <-c.done:
+				d.take(c)
+				if d.err != nil {
+					d.Close() // nothing more to read, so stop the reader early
+				}
+			case <-d.quit:
+				d.err = errClosed
+			}
+		case <-d.quit:
+			d.err = errClosed
+		}
+	}
+	n = copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// take makes the output of c, the next chunk in order, ready to be returned.
+func (d *parallelDecoder) take(c *parallelChunk) {
+	in, out := c.in, c.out
+	n, nsrc, nskip, err := c.n, c.nsrc, c.nskip, c.err
+	if len(d.carry) > 0 {
+		// The previous chunk did not end on a rune boundary, so c has to be
+		// decoded again, following on from where the previous one left off.
+		in = append(d.carry, in...)
+		out = make([]byte, d.enc.DecodedLen(len(in)))
+		n, nsrc, nskip, err = d.enc.decodeChunk(out, in)
+	}
+
+	d.out = out[:n]
+	if err != nil {
+		d.err = relocate(err, d.off, d.index)
+	}
+	d.carry = append([]byte(nil), in[nsrc:]...)
+	d.off += int64(nsrc)
+	d.index += int64(n + nskip)
+
+	if c.last && d.err == nil {
+		switch {
+		case c.readErr != nil:
+			d.err = c.readErr
+		case len(d.carry) > 0:
+			d.err = d.enc.trailingError(d.carry, d.off, d.index)
+		default:
+			d.err = io.EOF
+		}
+	}
+}
+
+// Close stops the goroutines of the decoder, once they finish what they are
+// doing. Subsequent reads return an error. It does not close the underlying
+// reader.
+func (d *parallelDecoder) Close() error {
+	select {
+	case <-d.quit:
+	default:
+		close(d.quit)
+	}
+	return nil
+}
+
+// reader feeds chunks of input to the workers, and the queue, until the end of
+// the input or an error. Chunks are split just before the last rune in them,
+// with the remainder carried over into the next.
+func (d *parallelDecoder) reader(r io.Reader) {
+	defer close(d.work)
+	var rest []byte
+	for {
+		buf := make([]byte, parallelChunkSize)
+		numCopy := copy(buf, rest)
+		numRead, err := io.ReadFull(r, buf[numCopy:])
+		buf = buf[:numCopy+numRead]
+
+		c := &parallelChunk{in: buf, done: make(chan struct{})}
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			c.last = true
+		case err != nil:
+			c.last, c.readErr = true, err
+		default:
+			if cut := bytes.LastIndexByte(buf, fixedByte1); cut > 0 {
+				c.in, rest = buf[:cut], buf[cut:]
+			} else {
+				rest = nil
+			}
+		}
+
+		select {
+		case d.queue <- c:
+		case <-d.quit:
+			return
+		}
+		select {
+		case d.work <- c:
+		case <-d.quit:
+			return
+		}
+		if c.last {
+			return
+		}
+	}
+}
+
+func (d *parallelDecoder) worker() {
+	for c := range d.work {
+		c.out = make([]byte, d.enc.DecodedLen(len(c.in)))
+		c.n, c.nsrc, c.nskip, c.err = d.enc.decodeChunk(c.out, c.in)
+		close(c.done)
+	}
+}
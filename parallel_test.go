@@ -0,0 +1,198 @@
+package base100
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// parallelData is long enough to be split several ways by both the slice and
+// stream functions.
+var parallelData = bytes.Repeat(allBytes(), 3*parallelChunkSize/256+17)
+
+var parallelEncodings = []struct {
+	name string
+	enc  *Encoding
+}{
+	{"std", StdEncoding},
+	{"strict", StdEncoding.Strict()},
+	{"wrap", StdEncoding.WithWrap(76, CRLF)},
+	{"presentation", StdEncoding.EmojiPresentation()},
+	{"wrap presentation", StdEncoding.EmojiPresentation().WithWrap(100, LF)},
+}
+
+// parallelInputs returns inputs to decode with enc: well formed, and broken in
+// a variety of ways part way through.
+func parallelInputs(enc *Encoding) map[string][]byte {
+	text := enc.AppendEncode(nil, parallelData)
+	mid := len(text)/2 + 1 // not on a rune boundary
+	return map[string][]byte{
+		"valid":     text,
+		"empty":     {},
+		"spaces":    bytes.ReplaceAll(text, []byte("🐗"), []byte(" 🐗\t")),
+		"garbage":   append(append(text[:mid:mid], "x\xf0"...), text[mid:]...),
+		"truncated": text[:len(text)-1],
+		"stray":     append(append(text[:mid:mid], 0xf0), text[mid+1:]...),
+	}
+}
+
+func TestEncodeParallel(t *testing.T) {
+	for _, tc := range parallelEncodings {
+		for _, jobs := range []int{0, 1, 3, 8} {
+			t.Run(fmt.Sprintf("%s/jobs=%d", tc.name, jobs), func(t *testing.T) {
+				want := tc.enc.AppendEncode(nil, parallelData)
+				dst := make([]byte, tc.enc.EncodedLen(len(parallelData)))
+				n := tc.enc.EncodeParallel(dst, parallelData, jobs)
+				if got := dst[:n]; !bytes.Equal(got, want) {
+					t.Errorf("EncodeParallel() differs from Encode(), %d bytes vs %d", len(got), len(want))
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeParallel(t *testing.T) {
+	for _, tc := range parallelEncodings {
+		for name, src := range parallelInputs(tc.enc) {
+			for _, jobs := range []int{0, 3, 8} {
+				t.Run(fmt.Sprintf("%s/%s/jobs=%d", tc.name, name, jobs), func(t *testing.T) {
+					want := make([]byte, tc.enc.DecodedLen(len(src)))
+					wantN, wantErr := tc.enc.Decode(want, src)
+					dst := make([]byte, tc.enc.DecodedLen(len(src)))
+					n, err := tc.enc.DecodeParallel(dst, src, jobs)
+					if err != wantErr {
+						t.Errorf("DecodeParallel() error = %v, want %v", err, wantErr)
+					}
+					if !bytes.Equal(dst[:n], want[:wantN]) {
+						t.Errorf("DecodeParallel() = %d bytes, want %d bytes of Decode()", n, wantN)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestParallelEncoder(t *testing.T) {
+	for _, tc := range parallelEncodings {
+		for _, jobs := range []int{0, 1, 4} {
+			t.Run(fmt.Sprintf("%s/jobs=%d", tc.name, jobs), func(t *testing.T) {
+				want := tc.enc.AppendEncode(nil, parallelData)
+				var buf bytes.Buffer
+				if err := writeChunks(tc.enc.NewParallelEncoder(&buf, jobs), parallelData, 100_000); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("parallel encoder differs from Encode(), %d bytes vs %d", buf.Len(), len(want))
+				}
+			})
+		}
+	}
+}
+
+func TestParallelEncoderErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	w := StdEncoding.NewParallelEncoder(errWriter{errBoom}, 2)
+	var err error
+	for range 10 {
+		if _, err = w.Write(parallelData[:parallelChunkSize]); err != nil {
+			break
+		}
+	}
+	if cerr := w.Close(); cerr != errBoom {
+		t.Errorf("Close() = %v, want %v", cerr, errBoom)
+	}
+	if err != nil && err != errBoom {
+		t.Errorf("Write() = %v, want %v", err, errBoom)
+	}
+	if _, err := w.Write([]byte("x")); err != errClosed {
+		t.Errorf("Write() after Close() = %v, want %v", err, errClosed)
+	}
+
+	var buf bytes.Buffer
+	w = StdEncoding.NewParallelEncoder(&limitWriter{w: &buf, limit: 100}, 2)
+	w.Write(parallelData[:1000])
+	if err := w.Close(); err != io.ErrShortWrite {
+		t.Errorf("Close() with short writes = %v, want %v", err, io.ErrShortWrite)
+	}
+}
+
+func TestParallelDecoder(t *testing.T) {
+	readers := []struct {
+		name string
+		wrap func(io.Reader) io.Reader
+	}{
+		{"reader", func(r io.Reader) io.Reader { return r }},
+		{"half", iotest.HalfReader},
+		{"data err", iotest.DataErrReader},
+	}
+	for _, tc := range parallelEncodings {
+		for name, src := range parallelInputs(tc.enc) {
+			want := make([]byte, tc.enc.DecodedLen(len(src)))
+			wantN, wantErr := tc.enc.Decode(want, src)
+			for _, jobs := range []int{0, 1, 4} {
+				for _, r := range readers {
+					t.Run(fmt.Sprintf("%s/%s/jobs=%d/%s", tc.name, name, jobs, r.name), func(t *testing.T) {
+						d := tc.enc.NewParallelDecoder(r.wrap(bytes.NewReader(src)), jobs)
+						defer d.Close()
+						got, err := io.ReadAll(d)
+						if err != wantErr {
+							t.Errorf("ReadAll() error = %v, want %v", err, wantErr)
+						}
+						if !bytes.Equal(got, want[:wantN]) {
+							t.Errorf("ReadAll() = %d bytes, want %d bytes of Decode()", len(got), wantN)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestParallelDecoderErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	text := EncodeToString(parallelData)
+	r := io.MultiReader(bytes.NewReader([]byte(text[:len(text)/2])), iotest.ErrReader(errBoom))
+	got, err := io.ReadAll(NewParallelDecoder(r, 2))
+	if err != errBoom {
+		t.Errorf("ReadAll() error = %v, want %v", err, errBoom)
+	}
+	if want := parallelData[:len(text)/8]; !bytes.Equal(got, want) {
+		t.Errorf("ReadAll() = %d bytes before the error, want %d", len(got), len(want))
+	}
+
+	d := NewParallelDecoder(bytes.NewReader([]byte(text)), 2)
+	if _, err := d.Read(make([]byte, 10)); err != nil {
+		t.Fatalf("Read() = %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	if _, err := io.ReadAll(d); err != errClosed {
+		t.Errorf("ReadAll() after Close() = %v, want %v", err, errClosed)
+	}
+}
+
+func BenchmarkEncodeParallel(b *testing.B) {
+	dst := make([]byte, EncodedLen(len(parallelData)))
+	b.SetBytes(int64(len(parallelData)))
+	for i := 0; i < b.N; i++ {
+		EncodeParallel(dst, parallelData, 0)
+	}
+}
+
+func BenchmarkDecodeParallel(b *testing.B) {
+	src := StdEncoding.Strict().AppendEncode(nil, parallelData)
+	dst := make([]byte, len(parallelData))
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		if _, err := StdEncoding.Strict().DecodeParallel(dst, src, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
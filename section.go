@@ -0,0 +1,141 @@
+package base100
+
+import (
+	"errors"
+	"io"
+)
+
+// Without wrapping, whitespace or variation selectors, every byte of input is
+// encoded as exactly one rune of encodedByteSize bytes, so the decoded byte at
+// any offset can be found without decoding everything before it.
+
+var (
+	errWhence         = errors.New("base100: invalid whence")
+	errNegativeOffset = errors.New("base100: negative offset")
+)
+
+// NewSectionDecoder returns a decoder of the size bytes of base100 encoded data
+// in r. See the SectionDecoder type for the requirements on the encoded data.
+func NewSectionDecoder(r io.ReaderAt, size int64) *SectionDecoder {
+	return StdEncoding.NewSectionDecoder(r, size)
+}
+
+// NewSectionDecoder returns a decoder of the size bytes of data in r encoded
+// with enc. Only the strictness and skin tone settings of enc apply, see the
+// SectionDecoder type.
+func (enc *Encoding) NewSectionDecoder(r io.ReaderAt, size int64) *SectionDecoder {
+	// Skipping over anything at all would break the 4:1 ratio.
+	plain := *enc
+	plain.whitespace, plain.presentation, plain.formatChars = false, false, false
+	return &SectionDecoder{enc: &plain, r: r, size: size}
+}
+
+// SectionDecoder decodes base100 data from an io.ReaderAt, allowing random
+// access to the decoded data, in the manner of an io.SectionReader. It
+// implements io.Reader, io.ReaderAt and io.Seeker, so it can be used with
+// io.SectionReader, http.ServeContent and the like.
+//
+// The encoded data must consist of runes only, with no wrapping, whitespace or
+// variation selectors, such that the decoded byte at offset k is encoded at
+// offset 4k. Anything else in the way is decoded into garbage, or reported as
+// a CorruptInputError if the decoder is strict. An incomplete rune at the very
+// end of the data is reported once a read reaches it.
+//
+// ReadAt may be called concurrently, as with any io.ReaderAt; Read and Seek
+// share an offset and may not.
+type SectionDecoder struct {
+	enc  *Encoding
+	r    io.ReaderAt
+	size int64 // of the encoded data
+	off  int64 // of the decoded data, for Read and Seek
+}
+
+// Size returns the size of the decoded data in bytes.
+func (d *SectionDecoder) Size() int64 {
+	return d.size / encodedByteSize
+}
+
+// Read implements the io.Reader interface.
+func (d *SectionDecoder) Read(p []byte) (n int, err error) {
+	n, err = d.ReadAt(p, d.off)
+	d.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil // as Read, unlike ReadAt, may return less than len(p)
+	}
+	return n, err
+}
+
+// Seek implements the io.Seeker interface, in terms of offsets of the decoded
+// data.
+func (d *SectionDecoder) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += d.off
+	case io.SeekEnd:
+		offset += d.Size()
+	default:
+		return 0, errWhence
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	d.off = offset
+	return offset, nil
+}
+
+// ReadAt implements the io.ReaderAt interface, reading len(p) decoded bytes
+// starting at offset off of the decoded data. Errors decoding the data report
+// positions within the whole of the encoded data.
+func (d *SectionDecoder) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	size := d.Size()
+	if off >= size {
+		return 0, d.endError()
+	}
+	if rest := size - off; int64(len(p)) > rest {
+		p = p[:rest]
+		defer func() {
+			if err == nil {
+				err = d.endError()
+			}
+		}()
+	}
+
+	var buf [bufferSize * encodedByteSize]byte
+	for n < len(p) {
+		pos := off + int64(n)
+		m := min(len(p)-n, bufferSize)
+		in := buf[:m*encodedByteSize]
+		numRead, readErr := d.r.ReadAt(in, pos*encodedByteSize)
+		in = in[:numRead-numRead%encodedByteSize]
+		k, _, _, err := d.enc.decodeChunk(p[n:n+m], in)
+		n += k
+		switch {
+		case err != nil:
+			return n, relocate(err, pos*encodedByteSize, pos)
+		case numRead < len(buf[:m*encodedByteSize]):
+			if readErr == nil || readErr == io.EOF {
+				readErr = io.ErrUnexpectedEOF // r is shorter than its given size
+			}
+			return n, readErr
+		}
+	}
+	return n, nil
+}
+
+// endError returns the error for reading up to the end of the decoded data:
+// io.EOF, unless the encoded data ends with an incomplete rune.
+func (d *SectionDecoder) endError() error {
+	tail := d.size % encodedByteSize
+	if tail == 0 {
+		return io.EOF
+	}
+	var buf [encodedByteSize]byte
+	if _, err := d.r.ReadAt(buf[:tail], d.size-tail); err != nil && err != io.EOF {
+		return err
+	}
+	return d.enc.trailingError(buf[:tail], d.size-tail, d.Size())
+}
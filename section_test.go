@@ -0,0 +1,154 @@
+package base100
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestSectionDecoder(t *testing.T) {
+	data := bytes.Repeat(allBytes(), 20) // spans several reads of the buffer
+	encodings := []struct {
+		name string
+		enc  *Encoding
+	}{
+		{"std", StdEncoding},
+		{"strict", StdEncoding.Strict()},
+		{"skin tone safe", StdEncoding.SkinToneSafe().Strict()},
+	}
+	for _, tc := range encodings {
+		t.Run(tc.name, func(t *testing.T) {
+			src := tc.enc.AppendEncode(nil, data)
+			d := tc.enc.NewSectionDecoder(bytes.NewReader(src), int64(len(src)))
+			if got, want := d.Size(), int64(len(data)); got != want {
+				t.Errorf("Size() = %d, want %d", got, want)
+			}
+			if err := iotest.TestReader(d, data); err != nil {
+				t.Error(err)
+			}
+
+			got, err := io.ReadAll(io.NewSectionReader(d, 1000, 3000))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := data[1000:4000]; !bytes.Equal(got, want) {
+				t.Errorf("SectionReader read %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSectionDecoderSeek(t *testing.T) {
+	src := []byte(EncodeToString([]byte("hello, world")))
+	d := NewSectionDecoder(bytes.NewReader(src), int64(len(src)))
+	tests := []struct {
+		offset  int64
+		whence  int
+		want    int64
+		wantErr error
+	}{
+		{7, io.SeekStart, 7, nil},
+		{-2, io.SeekCurrent, 5, nil},
+		{-5, io.SeekEnd, 7, nil},
+		{100, io.SeekStart, 100, nil}, // past the end is allowed
+		{-1, io.SeekStart, 0, errNegativeOffset},
+		{0, 42, 0, errWhence},
+	}
+	for _, tt := range tests {
+		got, err := d.Seek(tt.offset, tt.whence)
+		if got != tt.want || err != tt.wantErr {
+			t.Errorf("Seek(%d, %d) = %d, %v, want %d, %v", tt.offset, tt.whence, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	d.Seek(7, io.SeekStart)
+	got, err := io.ReadAll(d)
+	if string(got) != "world" || err != nil {
+		t.Errorf("ReadAll() after Seek = %q, %v, want %q, nil", got, err, "world")
+	}
+}
+
+func TestSectionDecoderErrors(t *testing.T) {
+	text := []byte(EncodeToString(bytes.Repeat(allBytes(), 10)))
+	buf := make([]byte, 100)
+
+	tests := []struct {
+		name    string
+		enc     *Encoding
+		src     []byte
+		size    int64
+		off     int64
+		wantN   int
+		wantErr error
+	}{
+		{
+			name:    "negative offset",
+			enc:     StdEncoding,
+			src:     text,
+			off:     -1,
+			wantErr: errNegativeOffset,
+		},
+		{
+			name:    "at end",
+			enc:     StdEncoding,
+			src:     text,
+			off:     2560,
+			wantErr: io.EOF,
+		},
+		{
+			name:    "across end",
+			enc:     StdEncoding,
+			src:     text,
+			off:     2500,
+			wantN:   60,
+			wantErr: io.EOF,
+		},
+		{
+			name:    "corrupt",
+			enc:     StdEncoding.Strict(),
+			src:     bytes.Replace(text, []byte("🐗"), []byte("xxxx"), 1), // byte 32
+			off:     10,
+			wantN:   22,
+			wantErr: CorruptInputError{Offset: 128, Index: 32},
+		},
+		{
+			name:    "whitespace",
+			enc:     StdEncoding.Strict(),
+			src:     append(text[:40:40], append([]byte("\n"), text[40:]...)...),
+			off:     0,
+			wantN:   10,
+			wantErr: CorruptInputError{Offset: 40, Index: 10},
+		},
+		{
+			name:    "truncated",
+			enc:     StdEncoding.Strict(),
+			src:     text[:len(text)-2],
+			off:     2500,
+			wantN:   59,
+			wantErr: TruncatedInputError(2),
+		},
+		{
+			name:    "short reader",
+			enc:     StdEncoding,
+			src:     text[:400],
+			size:    int64(len(text)),
+			off:     50,
+			wantN:   50,
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := tt.size
+			if size == 0 {
+				size = int64(len(tt.src))
+			}
+			d := tt.enc.NewSectionDecoder(bytes.NewReader(tt.src), size)
+			n, err := d.ReadAt(buf, tt.off)
+			if n != tt.wantN || err != tt.wantErr {
+				t.Errorf("ReadAt() = %d, %v, want %d, %v", n, err, tt.wantN, tt.wantErr)
+			}
+		})
+	}
+}
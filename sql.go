@@ -0,0 +1,55 @@
+package base100
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+var errScanNull = errors.New("base100: cannot scan NULL into Bytes, use NullBytes")
+
+// Value implements driver.Valuer, storing b as base100 text.
+func (b Bytes) Value() (driver.Value, error) {
+	return EncodeToString(b), nil
+}
+
+// Scan implements sql.Scanner, strictly decoding base100 text stored as either
+// a string or []byte, and reusing the capacity of b where there is enough. To
+// scan a column which may be NULL, use NullBytes.
+func (b *Bytes) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return b.UnmarshalText([]byte(src))
+	case []byte:
+		return b.UnmarshalText(src)
+	case nil:
+		return errScanNull
+	}
+	return fmt.Errorf("base100: cannot scan %T into Bytes", src)
+}
+
+// NullBytes is a Bytes which may be null, for use as a scan destination and a
+// query argument, in the manner of sql.NullString.
+type NullBytes struct {
+	Bytes Bytes
+	Valid bool // Valid is true if Bytes is not NULL
+}
+
+// Value implements driver.Valuer.
+func (n NullBytes) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Bytes.Value()
+}
+
+// Scan implements sql.Scanner.
+func (n *NullBytes) Scan(src any) error {
+	if src == nil {
+		n.Bytes, n.Valid = nil, false
+		return nil
+	}
+	err := n.Bytes.Scan(src)
+	n.Valid = err == nil
+	return err
+}
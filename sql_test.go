@@ -0,0 +1,187 @@
+package base100
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeDriver is a database/sql driver for a single table of one TEXT column,
+// which is all that is needed to see values make the round trip. Rows are
+// inserted with "INSERT", and read back with "SELECT" as strings, or with
+// "SELECT BYTES" as []byte, as various real drivers return text.
+type fakeDriver struct{}
+
+type fakeConn struct{ rows *[]driver.Value }
+
+type fakeStmt struct {
+	conn  fakeConn
+	query string
+}
+
+type fakeRows struct {
+	rows  []driver.Value
+	bytes bool
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{rows: new([]driver.Value)}, nil
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if !strings.HasPrefix(s.query, "INSERT") {
+		return nil, errors.New("unknown statement: " + s.query)
+	}
+	*s.conn.rows = append(*s.conn.rows, args[0])
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "SELECT") {
+		return nil, errors.New("unknown query: " + s.query)
+	}
+	return &fakeRows{rows: *s.conn.rows, bytes: s.query == "SELECT BYTES"}, nil
+}
+
+func (r *fakeRows) Columns() []string { return []string{"value"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.rows[0]
+	if s, ok := dest[0].(string); ok && r.bytes {
+		dest[0] = []byte(s)
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("base100fake", fakeDriver{})
+}
+
+func openFakeDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("base100fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1) // the table lives in the connection
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestBytesSQL(t *testing.T) {
+	db := openFakeDB(t)
+	values := []Bytes{Bytes("hello"), Bytes{}, Bytes(allBytes())}
+	for _, v := range values {
+		if _, err := db.Exec("INSERT ?", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, query := range []string{"SELECT", "SELECT BYTES"} {
+		t.Run(query, func(t *testing.T) {
+			rows, err := db.Query(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var got []string
+			for rows.Next() {
+				var b Bytes
+				if err := rows.Scan(&b); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, string(b))
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(values) {
+				t.Fatalf("got %d rows, want %d", len(got), len(values))
+			}
+			for i, v := range values {
+				if got[i] != string(v) {
+					t.Errorf("row %d = %q, want %q", i, got[i], v)
+				}
+			}
+		})
+	}
+
+	// Stored as text, for anything else reading the column.
+	var text string
+	if err := db.QueryRow("SELECT").Scan(&text); err != nil || text != "👟👜👣👣👦" {
+		t.Errorf("Scan() as string = %q, %v, want %q", text, err, "👟👜👣👣👦")
+	}
+}
+
+func TestNullBytesSQL(t *testing.T) {
+	db := openFakeDB(t)
+	values := []NullBytes{{Bytes: Bytes("hello"), Valid: true}, {}, {Bytes: Bytes("ignored")}}
+	for _, v := range values {
+		if _, err := db.Exec("INSERT ?", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := db.Query("SELECT BYTES")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []NullBytes
+	for rows.Next() {
+		n := NullBytes{Bytes: Bytes("stale"), Valid: true}
+		if err := rows.Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, n)
+	}
+	want := []NullBytes{{Bytes: Bytes("hello"), Valid: true}, {}, {}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if string(got[i].Bytes) != string(want[i].Bytes) || got[i].Valid != want[i].Valid {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBytesScanErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  any
+	}{
+		{"null", nil},
+		{"not base100", "hello"},
+		{"not base100 bytes", []byte("hello")},
+		{"int", int64(42)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Bytes
+			if err := b.Scan(tt.src); err == nil {
+				t.Errorf("Scan(%#v) succeeded with %q, want an error", tt.src, b)
+			}
+		})
+	}
+
+	var n NullBytes
+	if err := n.Scan("hello"); err == nil || n.Valid {
+		t.Errorf("NullBytes.Scan() of invalid base100 = %v, Valid %v, want an error", err, n.Valid)
+	}
+}
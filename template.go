@@ -0,0 +1,65 @@
+package base100
+
+import "fmt"
+
+// FuncMap returns functions for use with both text/template and html/template,
+// by way of their Funcs methods:
+//
+//	base100Encode DATA        encodes DATA, a string or []byte
+//	base100Decode TEXT        strictly decodes TEXT, a string or []byte, to a string
+//	base100Wrap WIDTH DATA    encodes DATA, with a line feed after every WIDTH runes
+//
+// For example, {{.Payload | base100Wrap 40}}. Invalid input is reported as an
+// error executing the template.
+func FuncMap() map[string]any {
+	return map[string]any{
+		"base100Encode": templateEncode,
+		"base100Decode": templateDecode,
+		"base100Wrap":   templateWrap,
+	}
+}
+
+// templateBytes returns the bytes of a template function argument.
+func templateBytes(name string, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case Bytes:
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported argument of type %T", name, v)
+}
+
+func templateEncode(v any) (string, error) {
+	src, err := templateBytes("base100Encode", v)
+	if err != nil {
+		return "", err
+	}
+	return EncodeToString(src), nil
+}
+
+func templateDecode(v any) (string, error) {
+	src, err := templateBytes("base100Decode", v)
+	if err != nil {
+		return "", err
+	}
+	dst := make([]byte, DecodedLen(len(src)))
+	n, err := DecodeStrict(dst, src)
+	if err != nil {
+		return "", err
+	}
+	return string(dst[:n]), nil
+}
+
+func templateWrap(width int, v any) (string, error) {
+	if width < 0 {
+		return "", fmt.Errorf("base100Wrap: negative wrap width %d", width)
+	}
+	src, err := templateBytes("base100Wrap", v)
+	if err != nil {
+		return "", err
+	}
+	return StdEncoding.WithWrap(width, LF).EncodeToString(src), nil
+}
@@ -0,0 +1,78 @@
+package base100
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestFuncMap(t *testing.T) {
+	data := map[string]any{
+		"Text":    "hi",
+		"Raw":     []byte("hi"),
+		"Bytes":   Bytes("hi"),
+		"Encoded": "👟👠",
+		"Markup":  EncodeToString([]byte("<b>")),
+	}
+	tests := []struct {
+		name string
+		text string
+		want string
+		html string // if different when escaped
+	}{
+		{"encode string", `{{base100Encode .Text}}`, "👟👠", ""},
+		{"encode bytes", `{{base100Encode .Raw}}`, "👟👠", ""},
+		{"encode Bytes", `{{.Bytes | base100Encode}}`, "👟👠", ""},
+		{"decode", `{{base100Decode .Encoded}}`, "hi", ""},
+		{"round trip", `{{.Text | base100Encode | base100Decode}}`, "hi", ""},
+		{"wrap", `{{.Text | base100Wrap 1}}`, "👟\n👠\n", ""},
+		{"wrap none", `{{base100Wrap 0 .Text}}`, "👟👠", ""},
+		{"escaped", `{{base100Decode .Markup}}`, "<b>", "&lt;b&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			tmpl := template.Must(template.New("").Funcs(FuncMap()).Parse(tt.text))
+			if err := tmpl.Execute(&buf, data); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("text/template output = %q, want %q", got, tt.want)
+			}
+
+			buf.Reset()
+			htmlTmpl := htmltemplate.Must(htmltemplate.New("").Funcs(FuncMap()).Parse(tt.text))
+			if err := htmlTmpl.Execute(&buf, data); err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if tt.html != "" {
+				want = tt.html
+			}
+			if got := buf.String(); got != want {
+				t.Errorf("html/template output = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFuncMapErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"invalid", `{{base100Decode "hello"}}`},
+		{"truncated", `{{base100Decode "👟\xf0\x9f"}}`},
+		{"wrong type", `{{base100Encode 42}}`},
+		{"negative width", `{{base100Wrap -1 "hi"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("").Funcs(FuncMap()).Parse(tt.text))
+			if err := tmpl.Execute(new(strings.Builder), nil); err == nil {
+				t.Error("Execute() succeeded, want an error")
+			}
+		})
+	}
+}